@@ -1,16 +1,33 @@
 package gosseract
 
 import (
+	"bytes"
+	"compress/zlib"
+	"context"
 	"encoding/xml"
+	"errors"
+	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
 	"io"
+	"io/fs"
 	"io/ioutil"
+	"math"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"testing/iotest"
+	"time"
 
 	. "github.com/otiai10/mint"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 )
 
 func TestMain(m *testing.M) {
@@ -35,21 +52,67 @@ func TestVersion(t *testing.T) {
 }
 
 func TestClearPersistentCache(t *testing.T) {
-	client := NewClient()
+	client, _ := NewClient()
 	defer client.Close()
 	client.init()
-	client.ClearPersistentCache()
+	Expect(t, client.ClearPersistentCache()).ToBe(nil)
 }
 
 func TestNewClient(t *testing.T) {
-	client := NewClient()
+	client, err := NewClient()
+	Expect(t, err).ToBe(nil)
 	defer client.Close()
 
 	Expect(t, client).TypeOf("*gosseract.Client")
 }
 
+func TestClient_WasmErrors(t *testing.T) {
+	client, _ := NewClient()
+	defer client.Close()
+
+	When(t, "the wasm binary does not export a function", func(t *testing.T) {
+		_, err := client.wasm.fun("NotExported")()
+		Expect(t, errors.Is(err, ErrMissingExport)).ToBe(true)
+	})
+
+	Because(t, "the memory of a trapped module cannot be trusted", func(t *testing.T) {
+		client, _ := NewClient()
+		defer client.Close()
+		client.SetImage("./test/data/001-helloworld.png")
+		client.wasm.err = &WasmError{Func: "UTF8Text", Kind: ErrWasmTrap}
+		_, err := client.Text()
+		Expect(t, errors.Is(err, ErrWasmTrap)).ToBe(true)
+	})
+
+	When(t, "the module cannot allocate memory", func(t *testing.T) {
+		_, err := client.wasm.alloc(math.MaxUint32)
+		Expect(t, errors.Is(err, ErrOutOfMemory)).ToBe(true)
+	})
+}
+
+func TestClient_Close(t *testing.T) {
+	client, _ := NewClient()
+	client.SetImage("./test/data/001-helloworld.png")
+	_, err := client.Text()
+	Expect(t, err).ToBe(nil)
+	Expect(t, client.Close()).ToBe(nil)
+
+	Because(t, "the wasm module is released", func(t *testing.T) {
+		Expect(t, errors.Is(client.ClearPersistentCache(), ErrClientClosed)).ToBe(true)
+		Expect(t, errors.Is(client.SetVariable(TESSEDIT_CHAR_WHITELIST, "H"), ErrClientClosed)).ToBe(true)
+		Expect(t, errors.Is(client.SetImage("./test/data/001-helloworld.png"), ErrClientClosed)).ToBe(true)
+		Expect(t, errors.Is(client.SetPageSegMode(PSM_SINGLE_LINE), ErrClientClosed)).ToBe(true)
+		_, err := client.Text()
+		Expect(t, errors.Is(err, ErrClientClosed)).ToBe(true)
+		_, err = client.GetBoundingBoxesVerbose()
+		Expect(t, errors.Is(err, ErrClientClosed)).ToBe(true)
+		Expect(t, client.Version()).ToBe("")
+		Expect(t, client.Close()).ToBe(nil)
+	})
+}
+
 func TestClient_SetTessdataPrefix(t *testing.T) {
-	client := NewClient()
+	client, _ := NewClient()
 	defer client.Close()
 
 	cwd, err := os.Getwd()
@@ -81,15 +144,118 @@ func TestClient_SetTessdataPrefix(t *testing.T) {
 	Expect(t, text).ToBe("Hello, World!")
 }
 
+func TestNewClientWithOptions(t *testing.T) {
+	content, err := ioutil.ReadFile("./test/data/001-helloworld.png")
+	Expect(t, err).ToBe(nil)
+
+	When(t, "no host directory is mounted", func(t *testing.T) {
+		client, err := NewClientWithOptions(ClientOptions{
+			HostFS: HostFSNone,
+			FS:     fstest.MapFS{"images/hello.png": {Data: content}},
+		})
+		Expect(t, err).ToBe(nil)
+		defer client.Close()
+
+		err = client.SetImage("/etc/hostname")
+		Expect(t, errors.Is(err, ErrPathNotAllowed)).ToBe(true)
+		err = client.SetConfigFile("../config/01.config")
+		Expect(t, errors.Is(err, ErrPathNotAllowed)).ToBe(true)
+		err = client.SetImage("./test/data/001-helloworld.png")
+		Expect(t, errors.Is(err, fs.ErrNotExist)).ToBe(true)
+
+		err = client.SetImage("images/hello.png")
+		Expect(t, err).ToBe(nil)
+		text, err := client.Text()
+		Expect(t, err).ToBe(nil)
+		Expect(t, text).ToBe("Hello, World!")
+	})
+
+	When(t, "only some host directories are mounted", func(t *testing.T) {
+		client, err := NewClientWithOptions(ClientOptions{
+			HostFS:      HostFSReadOnlyDirs,
+			AllowedDirs: []string{"./test/data"},
+		})
+		Expect(t, err).ToBe(nil)
+		defer client.Close()
+
+		err = client.SetConfigFile("./test/config/01.config")
+		Expect(t, errors.Is(err, ErrPathNotAllowed)).ToBe(true)
+		err = client.SetTessdataPrefix("/usr/share/tessdata")
+		Expect(t, errors.Is(err, ErrPathNotAllowed)).ToBe(true)
+		err = client.SetImage("./test/data/../config/01.config")
+		Expect(t, errors.Is(err, ErrPathNotAllowed)).ToBe(true)
+
+		err = client.SetImage("./test/data/001-helloworld.png")
+		Expect(t, err).ToBe(nil)
+		text, err := client.Text()
+		Expect(t, err).ToBe(nil)
+		Expect(t, text).ToBe("Hello, World!")
+	})
+
+	When(t, "an allowed dir does not exist", func(t *testing.T) {
+		_, err := NewClientWithOptions(ClientOptions{
+			HostFS:      HostFSReadOnlyDirs,
+			AllowedDirs: []string{"./test/not-existing"},
+		})
+		Expect(t, err).Not().ToBe(nil)
+	})
+}
+
+func TestNew(t *testing.T) {
+	client, err := New(
+		WithLanguages("eng"),
+		WithPageSegMode(PSM_SINGLE_BLOCK),
+		WithVariables(map[SettableVariable]string{TESSEDIT_CHAR_BLACKLIST: "!"}),
+		WithOCREngineMode(OEM_LSTM_ONLY),
+		WithMemoryLimit(512*1024*1024),
+	)
+	Require(t, err).ToBe(nil)
+	defer client.Close()
+
+	client.SetImage("./test/data/001-helloworld.png")
+	text, err := client.Text()
+	Expect(t, err).ToBe(nil)
+	Expect(t, text).Match("Hello, World")
+
+	When(t, "an option is invalid", func(t *testing.T) {
+		_, err := New(WithLanguages())
+		Expect(t, err).Not().ToBe(nil)
+		_, err = New(WithPageSegMode(PSM_COUNT))
+		Expect(t, err).Not().ToBe(nil)
+		_, err = New(WithOCREngineMode(OEM_TESSERACT_ONLY))
+		Expect(t, err).Not().ToBe(nil)
+	})
+
+	Because(t, "the configuration is checked before New returns", func(t *testing.T) {
+		_, err := New(WithLanguages("undefined-language"))
+		Expect(t, err).Not().ToBe(nil)
+		_, err = New(WithVariables(map[SettableVariable]string{"foobar": "hoge"}))
+		Expect(t, err).Not().ToBe(nil)
+		_, err = New(WithConfigFile("./test/config/not-existing"))
+		Expect(t, err).Not().ToBe(nil)
+		_, err = New(WithMemoryLimit(wasmPageSize))
+		Expect(t, err).Not().ToBe(nil)
+	})
+
+	When(t, "the memory limit is out of the range of wasm32", func(t *testing.T) {
+		_, err := New(WithMemoryLimit(wasmPageSize - 1))
+		Expect(t, err).Not().ToBe(nil)
+		_, err = New(WithMemoryLimit(8 << 30))
+		Expect(t, err).Not().ToBe(nil)
+		_, err = NewClientWithOptions(ClientOptions{MemoryLimit: 8 << 30})
+		Expect(t, err).Not().ToBe(nil)
+	})
+}
+
 func TestClient_Version(t *testing.T) {
-	client := NewClient()
+	client, _ := NewClient()
 	defer client.Close()
 	version := client.Version()
 	Expect(t, version).Match("[0-9]{1}.[0-9]{1,2}(.[0-9a-z_-]*)?")
 }
 
 func TestClient_SetImage(t *testing.T) {
-	client := NewClient()
+	client, _ := NewClient()
 	defer client.Close()
 
 	client.Trim = true
@@ -124,7 +290,7 @@ func TestClient_SetImage(t *testing.T) {
 }
 
 func TestClient_SetImageFromBytes(t *testing.T) {
-	client := NewClient()
+	client, _ := NewClient()
 	defer client.Close()
 
 	content, err := ioutil.ReadFile("./test/data/001-helloworld.png")
@@ -162,7 +328,7 @@ func TestClient_SetWhitelist(t *testing.T) {
 		t.Skip("Whitelist with LSTM is not working for now. Please check https://github.com/tesseract-ocr/tesseract/issues/751")
 	}
 
-	client := NewClient()
+	client, _ := NewClient()
 	defer client.Close()
 
 	client.Trim = true
@@ -182,7 +348,7 @@ func TestClient_SetBlacklist(t *testing.T) {
 		t.Skip("Blacklist with LSTM is not working for now. Please check https://github.com/tesseract-ocr/tesseract/issues/751")
 	}
 
-	client := NewClient()
+	client, _ := NewClient()
 	defer client.Close()
 
 	client.Trim = true
@@ -197,7 +363,7 @@ func TestClient_SetBlacklist(t *testing.T) {
 }
 
 func TestClient_SetLanguage(t *testing.T) {
-	client := NewClient()
+	client, _ := NewClient()
 	defer client.Close()
 	err := client.SetLanguage("undefined-language")
 	Expect(t, err).ToBe(nil)
@@ -208,13 +374,56 @@ func TestClient_SetLanguage(t *testing.T) {
 	Expect(t, err).Not().ToBe(nil)
 }
 
+func TestClient_SetOcrEngineMode(t *testing.T) {
+	client, _ := NewClient()
+	defer client.Close()
+
+	err := client.SetOcrEngineMode(OEM_LSTM_ONLY)
+	Expect(t, err).ToBe(nil)
+	Expect(t, client.shouldInit).ToBe(true)
+	client.SetImage("./test/data/001-helloworld.png")
+	text, err := client.Text()
+	Expect(t, err).ToBe(nil)
+	Expect(t, text).ToBe("Hello, World!")
+
+	When(t, "the mode is invalid", func(t *testing.T) {
+		err := client.SetOcrEngineMode(OEM_COUNT)
+		Expect(t, err).Not().ToBe(nil)
+	})
+
+	When(t, "the mode requires the legacy engine", func(t *testing.T) {
+		if legacy, _ := client.wasm.hasLegacyEngine(); legacy {
+			t.Skip("tesseract is built with the legacy engine")
+		}
+		err := client.SetOcrEngineMode(OEM_TESSERACT_ONLY)
+		Expect(t, errors.Is(err, ErrLegacyEngineDisabled)).ToBe(true)
+		err = client.SetOcrEngineMode(OEM_TESSERACT_LSTM_COMBINED)
+		Expect(t, errors.Is(err, ErrLegacyEngineDisabled)).ToBe(true)
+		_, err = New(WithOCREngineMode(OEM_TESSERACT_ONLY))
+		Expect(t, errors.Is(err, ErrLegacyEngineDisabled)).ToBe(true)
+		Because(t, "the previous mode is kept", func(t *testing.T) {
+			Expect(t, client.ocrEngineMode).ToBe(OEM_LSTM_ONLY)
+		})
+		Because(t, "the mode is passed to TessBaseAPI::Init, which refuses it as well", func(t *testing.T) {
+			client, _ := NewClient()
+			defer client.Close()
+			client.SetImage("./test/data/001-helloworld.png")
+			// Bypass the check of SetOcrEngineMode.
+			client.ocrEngineMode = OEM_TESSERACT_ONLY
+			client.flagForInit()
+			_, err := client.Text()
+			Expect(t, err).Not().ToBe(nil)
+		})
+	})
+}
+
 func TestClient_ConfigFilePath(t *testing.T) {
 
 	if os.Getenv("TESS_LSTM_DISABLED") == "1" {
 		t.Skip("Whitelist with LSTM is not working for now. Please check https://github.com/tesseract-ocr/tesseract/issues/751")
 	}
 
-	client := NewClient()
+	client, _ := NewClient()
 	defer client.Close()
 
 	err := client.SetConfigFile("./test/config/01.config")
@@ -237,13 +446,54 @@ func TestClient_ConfigFilePath(t *testing.T) {
 
 }
 
+func TestClient_TextContext(t *testing.T) {
+	client, _ := NewClient()
+	defer client.Close()
+	client.SetImage("./test/data/001-helloworld.png")
+
+	text, err := client.TextContext(context.Background())
+	Expect(t, err).ToBe(nil)
+	Expect(t, text).ToBe("Hello, World!")
+
+	When(t, "the context is already done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := client.HOCRTextContext(ctx)
+		Expect(t, err).ToBe(context.Canceled)
+		Because(t, "nothing was run, the client is still usable", func(t *testing.T) {
+			text, err := client.Text()
+			Expect(t, err).ToBe(nil)
+			Expect(t, text).ToBe("Hello, World!")
+		})
+	})
+
+	When(t, "the context is canceled during recognition", func(t *testing.T) {
+		client, _ := NewClient()
+		defer client.Close()
+		client.SetImage("./test/data/003-longer-text.png")
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		// The context is canceled right as the recognition starts, the recognition takes far longer
+		// than wazero needs to notice.
+		getBoundingBoxes := client.wasm.GetBoundingBoxes
+		client.wasm.GetBoundingBoxes = func(params ...uint64) ([]uint64, error) {
+			cancel()
+			return getBoundingBoxes(params...)
+		}
+		_, err := client.GetBoundingBoxesContext(ctx, RIL_WORD)
+		Expect(t, err).ToBe(context.Canceled)
+		_, err = client.Text()
+		Expect(t, errors.Is(err, ErrClientClosed)).ToBe(true)
+	})
+}
+
 func TestClientBoundingBox(t *testing.T) {
 
 	if os.Getenv("TESS_BOX_DISABLED") == "1" {
 		t.Skip()
 	}
 
-	client := NewClient()
+	client, _ := NewClient()
 	defer client.Close()
 	client.SetImage("./test/data/001-helloworld.png")
 	client.SetWhitelist("Hello,World!")
@@ -268,6 +518,360 @@ func TestClientBoundingBox(t *testing.T) {
 		Expect(t, box.Word).ToBe(words[i])
 		Expect(t, box.Box).ToBe(coords[i])
 	}
+
+	When(t, "there are many words", func(t *testing.T) {
+		client, _ := NewClient()
+		defer client.Close()
+		client.SetImage("./test/data/003-longer-text.png")
+		boxes, err := client.GetBoundingBoxesVerbose()
+		Expect(t, err).ToBe(nil)
+		Expect(t, boxes[1].Word).ToBe("out")
+		Expect(t, boxes[1].Box).ToBe(image.Rect(180, 49, 220, 70))
+		Expect(t, boxes[1].Confidence > 80).ToBe(true)
+		Expect(t, boxes[1].WordNum).ToBe(2)
+		last := boxes[len(boxes)-1]
+		Expect(t, last.LineNum).ToBe(4)
+	})
+
+	Because(t, "struct bounding_box is 40 bytes with a float confidence in wasm32", func(t *testing.T) {
+		client, _ := NewClient()
+		defer client.Close()
+		client.SetImage("./test/data/001-helloworld.png")
+		boxes, err := client.GetBoundingBoxes(RIL_WORD)
+		Expect(t, err).ToBe(nil)
+		Expect(t, len(boxes)).ToBe(2)
+		Expect(t, boxes[1].Word).ToBe("World!")
+		Expect(t, boxes[1].Box).ToBe(image.Rect(638, 64, 1099, 170))
+		for _, box := range boxes {
+			Expect(t, box.Confidence > 50 && box.Confidence <= 100).ToBe(true)
+		}
+	})
+}
+
+func TestClient_SetRectangle(t *testing.T) {
+	client, _ := NewClient()
+	defer client.Close()
+	client.SetImage("./test/data/003-longer-text.png")
+
+	When(t, "the rectangle is empty", func(t *testing.T) {
+		Expect(t, client.SetRectangle(image.Rect(10, 10, 10, 20))).Not().ToBe(nil)
+		Expect(t, client.SetRectangle(image.Rect(-10, 0, 20, 20))).Not().ToBe(nil)
+		_, err := client.TextInRegions([]image.Rectangle{{}})
+		Expect(t, err).Not().ToBe(nil)
+	})
+
+	// The expectations are the ones of the same regions cropped from the image.
+	err := client.SetRectangle(image.Rect(70, 40, 340, 80))
+	Expect(t, err).ToBe(nil)
+	text, err := client.Text()
+	Expect(t, err).ToBe(nil)
+	Expect(t, text).ToBe("Writing out a longer")
+
+	Because(t, "bounding boxes are in coordinates of the whole image", func(t *testing.T) {
+		boxes, err := client.GetBoundingBoxes(RIL_WORD)
+		Expect(t, err).ToBe(nil)
+		Require(t, len(boxes)).ToBe(4)
+		Expect(t, boxes[0].Word).ToBe("Writing")
+		Expect(t, boxes[0].Box).ToBe(image.Rect(80, 49, 169, 76))
+	})
+
+	When(t, "the rectangle is cleared", func(t *testing.T) {
+		client.ClearRectangle()
+		text, err := client.Text()
+		Expect(t, err).ToBe(nil)
+		Expect(t, text).Match("^Writing out a longer document")
+	})
+
+	When(t, "many regions are recognized", func(t *testing.T) {
+		texts, err := client.TextInRegions([]image.Rectangle{
+			image.Rect(70, 40, 340, 80),
+			image.Rect(70, 156, 360, 200),
+		})
+		Expect(t, err).ToBe(nil)
+		Expect(t, len(texts)).ToBe(2)
+		Expect(t, texts[0]).ToBe("Writing out a longer")
+		Expect(t, texts[1]).Match("^be approved.")
+	})
+}
+
+func TestClient_SetSourceResolution(t *testing.T) {
+	client, _ := NewClient()
+	defer client.Close()
+	client.SetImage("./test/data/001-helloworld.png")
+
+	err := client.SetSourceResolution(-1)
+	Expect(t, err).Not().ToBe(nil)
+
+	out, err := client.HOCRText()
+	Expect(t, err).ToBe(nil)
+	Expect(t, out).Match("scan_res 144 144")
+
+	err = client.SetSourceResolution(300)
+	Expect(t, err).ToBe(nil)
+	out, err = client.HOCRText()
+	Expect(t, err).ToBe(nil)
+	Expect(t, out).Match("scan_res 300 300")
+	dpi, err := client.GetSourceResolution()
+	Expect(t, err).ToBe(nil)
+	Expect(t, dpi).ToBe(300)
+
+	When(t, "the image has no resolution", func(t *testing.T) {
+		client.SetSourceResolution(0)
+		err := client.SetImageFromImage(image.NewGray(image.Rect(0, 0, 100, 100)))
+		Expect(t, err).ToBe(nil)
+		dpi, err := client.GetSourceResolution()
+		Expect(t, err).ToBe(nil)
+		Expect(t, dpi).ToBe(70)
+		Because(t, "the clamp can be disabled", func(t *testing.T) {
+			err := client.SetResolutionClamp(false)
+			Expect(t, err).ToBe(nil)
+			defer client.SetResolutionClamp(true)
+			dpi, err := client.GetSourceResolution()
+			Expect(t, err).ToBe(nil)
+			Expect(t, dpi).Not().ToBe(70)
+			_, err = client.Text()
+			Expect(t, err).ToBe(nil)
+		})
+	})
+
+	When(t, "the client is closed", func(t *testing.T) {
+		client, _ := NewClient()
+		client.Close()
+		Expect(t, errors.Is(client.SetSourceResolution(300), ErrClientClosed)).ToBe(true)
+		Expect(t, errors.Is(client.SetResolutionClamp(false), ErrClientClosed)).ToBe(true)
+	})
+}
+
+func TestClient_Recognize(t *testing.T) {
+	client, _ := NewClient()
+	defer client.Close()
+
+	_, err := client.Recognize()
+	Expect(t, err).Not().ToBe(nil)
+
+	client.SetImage("./test/data/003-longer-text.png")
+	page, err := client.Recognize()
+	Require(t, err).ToBe(nil)
+
+	text, err := client.Text()
+	Expect(t, err).ToBe(nil)
+	Expect(t, strings.Trim(page.Text(), "\n")).ToBe(text)
+	Expect(t, len(page.Blocks)).ToBe(1)
+	Expect(t, len(page.Blocks[0].Paragraphs)).ToBe(1)
+	Expect(t, len(page.Blocks[0].Paragraphs[0].Lines)).ToBe(4)
+	Expect(t, page.Blocks[0].Paragraphs[0].Justification).ToBe(JUSTIFICATION_LEFT)
+	Expect(t, page.Blocks[0].BlockType).ToBe(PT_FLOWING_TEXT)
+
+	line := page.Blocks[0].Paragraphs[0].Lines[0]
+	Expect(t, line.Orientation).ToBe(ORIENTATION_PAGE_UP)
+	Expect(t, line.WritingDirection).ToBe(WRITING_DIRECTION_LEFT_TO_RIGHT)
+	Expect(t, line.TextlineOrder).ToBe(TEXTLINE_ORDER_TOP_TO_BOTTOM)
+	Expect(t, line.Baseline).Not().ToBe(nil)
+	Expect(t, line.Baseline.Start.Y > line.Box.Min.Y && line.Baseline.Start.Y < line.Box.Max.Y).ToBe(true)
+
+	words := page.Words()
+	Expect(t, words[0].Text).ToBe("Writing")
+	Expect(t, words[0].Box).ToBe(image.Rect(80, 49, 169, 76))
+	Expect(t, words[0].Language).ToBe("eng")
+	Expect(t, words[0].PointSize > 0).ToBe(true)
+	if legacy, _ := client.wasm.hasLegacyEngine(); !legacy {
+		Expect(t, words[0].FontName).ToBe("")
+	}
+	Expect(t, len(words[0].Symbols)).ToBe(7)
+	Expect(t, words[0].Symbols[0].Text).ToBe("W")
+}
+
+func TestClient_AnalyseLayout(t *testing.T) {
+	client, _ := NewClient()
+	defer client.Close()
+	client.SetImage("./test/data/003-longer-text.png")
+
+	page, err := client.AnalyseLayout()
+	Require(t, err).ToBe(nil)
+	Expect(t, len(page.Blocks)).ToBe(1)
+	Expect(t, page.Blocks[0].BlockType.IsText()).ToBe(true)
+	Expect(t, page.Text()).ToBe("")
+	Expect(t, len(page.Words()) > 0).ToBe(true)
+}
+
+func TestPolyBlockType(t *testing.T) {
+	Expect(t, PT_FLOWING_TEXT.IsText()).ToBe(true)
+	Expect(t, PT_TABLE.IsText()).ToBe(true)
+	Expect(t, PT_FLOWING_IMAGE.IsText()).ToBe(false)
+	Expect(t, PT_FLOWING_IMAGE.IsImage()).ToBe(true)
+	Expect(t, PT_VERT_LINE.IsLine()).ToBe(true)
+	Expect(t, PT_NOISE.IsLine()).ToBe(false)
+}
+
+func TestNewResultPage(t *testing.T) {
+	box := image.Rect(0, 0, 10, 10)
+	page := newResultPage([]resultRecord{
+		{level: RIL_BLOCK, Result: Result{Box: box, Text: "a b\n\n"}},
+		{level: RIL_PARA, Result: Result{Box: box, Text: "a b\n"}, paragraph: ParagraphInfo{IsListItem: true}},
+		{level: RIL_TEXTLINE, Result: Result{Box: box, Text: "a b\n"}, baseline: &Baseline{image.Pt(0, 8), image.Pt(10, 8)}},
+		{level: RIL_WORD, Result: Result{Box: box, Text: "a"}, language: "eng"},
+		{level: RIL_SYMBOL, Result: Result{Box: box, Text: "a"}, superscript: true},
+		{level: RIL_WORD, Result: Result{Box: box, Text: "b"}, numeric: true, font: FontAttributes{IsBold: true, PointSize: 12}},
+		{level: RIL_SYMBOL, Result: Result{Box: box, Text: "b"}},
+		{level: RIL_BLOCK, Result: Result{Box: box}, blockType: PT_HORZ_LINE},
+	})
+	Expect(t, len(page.Blocks)).ToBe(2)
+	Expect(t, page.Text()).ToBe("a b\n\n")
+	Expect(t, len(page.Words())).ToBe(2)
+	Expect(t, page.Words()[0].Language).ToBe("eng")
+	Expect(t, page.Words()[0].Symbols[0].Superscript).ToBe(true)
+	Expect(t, page.Words()[1].Numeric).ToBe(true)
+	Expect(t, page.Words()[1].IsBold).ToBe(true)
+	Expect(t, page.Words()[1].PointSize).ToBe(12)
+	Expect(t, len(page.Blocks[1].Paragraphs)).ToBe(0)
+	Expect(t, page.Blocks[1].BlockType).ToBe(PT_HORZ_LINE)
+	Expect(t, page.Blocks[0].Paragraphs[0].IsListItem).ToBe(true)
+	Expect(t, page.Blocks[0].Paragraphs[0].Lines[0].Baseline.End).ToBe(image.Pt(10, 8))
+
+	When(t, "parents are missing", func(t *testing.T) {
+		page := newResultPage([]resultRecord{{level: RIL_SYMBOL, Result: Result{Text: "a"}}})
+		Expect(t, len(page.Words())).ToBe(1)
+		Expect(t, page.Words()[0].Symbols[0].Text).ToBe("a")
+	})
+}
+
+func TestClient_GetSymbolChoices(t *testing.T) {
+	client, _ := NewClient()
+	defer client.Close()
+	client.SetImage("./test/data/001-helloworld.png")
+
+	symbols, err := client.GetSymbolChoices()
+	Require(t, err).ToBe(nil)
+	Expect(t, len(symbols)).ToBe(len("Hello,World!"))
+	Expect(t, symbols[0].Text).ToBe("H")
+	Expect(t, symbols[0].Choices[0].Text).ToBe("H")
+	Expect(t, len(symbols[0].Timesteps)).ToBe(0)
+
+	When(t, "lstm_choice_mode is set", func(t *testing.T) {
+		client.SetVariable("lstm_choice_mode", "2")
+		symbols, err := client.GetSymbolChoices()
+		Require(t, err).ToBe(nil)
+		Require(t, len(symbols[0].Timesteps) > 0).ToBe(true)
+		Because(t, "timesteps are probabilities in percent like the other confidences", func(t *testing.T) {
+			best := 0.0
+			for _, timestep := range symbols[0].Timesteps {
+				for _, choice := range timestep {
+					Expect(t, choice.Confidence >= 0 && choice.Confidence <= 100).ToBe(true)
+					best = math.Max(best, choice.Confidence)
+				}
+			}
+			Expect(t, best > 1).ToBe(true)
+		})
+	})
+}
+
+func TestNewSymbolResults(t *testing.T) {
+	symbols := newSymbolResults([]choiceRecord{
+		{kind: choiceSymbol, Result: Result{Text: "0", Confidence: 90}},
+		{kind: choiceAlternative, Result: Result{Text: "0", Confidence: 90}},
+		{kind: choiceAlternative, Result: Result{Text: "O", Confidence: 60}},
+		{kind: choiceTimestep},
+		{kind: choiceTimestepAlternative, Result: Result{Text: "0", Confidence: 0.75}},
+		{kind: choiceTimestep},
+		{kind: choiceSymbol, Result: Result{Text: "1", Confidence: 95}},
+	})
+	Expect(t, len(symbols)).ToBe(2)
+	Expect(t, symbols[0].Choices).ToBe([]SymbolChoice{{"0", 90}, {"O", 60}})
+	Expect(t, symbols[0].Timesteps).ToBe([][]SymbolChoice{{{"0", 75}}, {}})
+	Expect(t, len(symbols[1].Choices)).ToBe(0)
+}
+
+func TestClient_TextWithConfidence(t *testing.T) {
+	client, _ := NewClient()
+	defer client.Close()
+
+	_, err := client.MeanTextConf()
+	Expect(t, err).Not().ToBe(nil)
+
+	client.SetImage("./test/data/003-longer-text.png")
+	When(t, "the settings change", func(t *testing.T) {
+		_, err := client.Text()
+		Expect(t, err).ToBe(nil)
+		Expect(t, client.imageSet).ToBe(true)
+		client.SetVariable(TESSEDIT_CHAR_BLACKLIST, "")
+		Expect(t, client.imageSet).ToBe(false)
+	})
+
+	result, err := client.TextWithConfidence()
+	Require(t, err).ToBe(nil)
+	Expect(t, result.Text).Match("^Writing out a longer document")
+	Expect(t, len(result.WordConfidences)).ToBe(len(strings.Fields(result.Text)))
+	Expect(t, result.MeanConfidence > 80).ToBe(true)
+
+	Because(t, "the last recognition is reused", func(t *testing.T) {
+		Expect(t, client.imageSet).ToBe(true)
+		confidences, err := client.AllWordConfidences()
+		Expect(t, err).ToBe(nil)
+		Expect(t, confidences).ToBe(result.WordConfidences)
+		mean, err := client.MeanTextConf()
+		Expect(t, err).ToBe(nil)
+		Expect(t, mean).ToBe(result.MeanConfidence)
+	})
+}
+
+func TestClient_RecognitionIsReused(t *testing.T) {
+	client, _ := NewClient()
+	defer client.Close()
+	client.SetImage("./test/data/001-helloworld.png")
+
+	// Count the calls which make TessBaseAPI initialize again or drop its last recognition.
+	var inits, images int
+	initAPI, setPixImage := client.wasm.Init, client.wasm.SetPixImage
+	client.wasm.Init = func(params ...uint64) ([]uint64, error) {
+		inits++
+		return initAPI(params...)
+	}
+	client.wasm.SetPixImage = func(params ...uint64) ([]uint64, error) {
+		images++
+		return setPixImage(params...)
+	}
+	text := func(t *testing.T) string {
+		inits, images = 0, 0
+		text, err := client.Text()
+		Expect(t, err).ToBe(nil)
+		return text
+	}
+
+	Expect(t, text(t)).ToBe("Hello, World!")
+	Expect(t, inits).ToBe(1)
+	Expect(t, images).ToBe(1)
+
+	When(t, "nothing changed", func(t *testing.T) {
+		Expect(t, text(t)).ToBe("Hello, World!")
+		Expect(t, inits).ToBe(0)
+		Expect(t, images).ToBe(0)
+	})
+
+	When(t, "only a variable changed", func(t *testing.T) {
+		client.SetBlacklist("!")
+		Expect(t, text(t)).Not().ToBe("Hello, World!")
+		Expect(t, inits).ToBe(0)
+		Expect(t, images).ToBe(1)
+	})
+
+	When(t, "only the image changed", func(t *testing.T) {
+		client.SetImage("./test/data/001-helloworld.png")
+		Expect(t, text(t)).Not().ToBe("Hello, World!")
+		Expect(t, inits).ToBe(0)
+		Expect(t, images).ToBe(1)
+	})
+
+	When(t, "the languages changed", func(t *testing.T) {
+		client.SetLanguage("eng")
+		Expect(t, text(t)).Not().ToBe("Hello, World!")
+		Expect(t, inits).ToBe(1)
+		Expect(t, images).ToBe(1)
+	})
+}
+
+func TestMeanTextConf(t *testing.T) {
+	Expect(t, meanTextConf(nil)).ToBe(0)
+	Expect(t, meanTextConf([]int{90, 95, 96})).ToBe(93)
 }
 
 func TestClient_HTML(t *testing.T) {
@@ -276,7 +880,7 @@ func TestClient_HTML(t *testing.T) {
 		t.Skip()
 	}
 
-	client := NewClient()
+	client, _ := NewClient()
 	defer client.Close()
 	client.SetImage("./test/data/001-helloworld.png")
 	client.SetWhitelist("Hello,World!")
@@ -294,7 +898,7 @@ func TestClient_HTML(t *testing.T) {
 	}
 
 	When(t, "only invalid languages are given", func(t *testing.T) {
-		client := NewClient()
+		client, _ := NewClient()
 		defer client.Close()
 		client.SetLanguage("foo")
 		client.SetImage("./test/data/001-helloworld.png")
@@ -302,7 +906,7 @@ func TestClient_HTML(t *testing.T) {
 		Expect(t, err).Not().ToBe(nil)
 	})
 	Because(t, "unknown key is validated when `init` is called", func(t *testing.T) {
-		client := NewClient()
+		client, _ := NewClient()
 		defer client.Close()
 		err := client.SetVariable("foobar", "hoge")
 		Expect(t, err).ToBe(nil)
@@ -312,9 +916,1037 @@ func TestClient_HTML(t *testing.T) {
 	})
 }
 
+func TestParseHOCR(t *testing.T) {
+	client, _ := NewClient()
+	defer client.Close()
+	client.SetImage("./test/data/001-helloworld.png")
+	client.SetVariable("hocr_char_boxes", "1")
+	out, err := client.HOCRText()
+	Expect(t, err).ToBe(nil)
+
+	doc, err := ParseHOCR(strings.NewReader(out))
+	Expect(t, err).ToBe(nil)
+	Expect(t, len(doc.Pages)).ToBe(1)
+	page := doc.Pages[0]
+	Expect(t, page.Class).ToBe("ocr_page")
+	img, ok := page.Title.Image()
+	Expect(t, ok).ToBe(true)
+	Expect(t, img).ToBe("unknown")
+	pageNo, ok := page.Title.PPageNo()
+	Expect(t, ok).ToBe(true)
+	Expect(t, pageNo).ToBe(0)
+	Expect(t, len(page.Areas)).ToBe(1)
+	Expect(t, len(page.Areas[0].Paragraphs)).ToBe(1)
+	Expect(t, page.Areas[0].Paragraphs[0].Lang).ToBe("eng")
+	line := page.Areas[0].Paragraphs[0].Lines[0]
+	Expect(t, line.Class).ToBe("ocr_line")
+	_, _, ok = line.Title.Baseline()
+	Expect(t, ok).ToBe(true)
+	Expect(t, len(line.Words)).ToBe(2)
+	Expect(t, line.Words[0].Text).ToBe("Hello,")
+	Expect(t, line.Words[1].Text).ToBe("World!")
+	Expect(t, len(line.Words[0].Chars)).ToBe(6)
+	box, ok := line.Words[0].Title.BBox()
+	Expect(t, ok).ToBe(true)
+	Expect(t, box.Min.X < box.Max.X && box.Min.Y < box.Max.Y).ToBe(true)
+	conf, ok := line.Words[0].Title.XWConf()
+	Expect(t, ok).ToBe(true)
+	Expect(t, conf > 50).ToBe(true)
+	charBox, ok := line.Words[0].Chars[0].Title.XBBoxes()
+	Expect(t, ok).ToBe(true)
+	Expect(t, charBox.In(box)).ToBe(true)
+
+	When(t, "the document is encoded again", func(t *testing.T) {
+		var buf strings.Builder
+		Expect(t, doc.Encode(&buf)).ToBe(nil)
+		again, err := ParseHOCR(strings.NewReader(buf.String()))
+		Expect(t, err).ToBe(nil)
+		Expect(t, again).Deeply().ToBe(doc)
+	})
+
+	When(t, "the document has many areas, paragraphs and kinds of lines", func(t *testing.T) {
+		doc, err := ParseHOCR(strings.NewReader(`<!DOCTYPE html>
+<html><head><title></title></head><body>
+<div class='ocr_page' id='page_1' title='image "/tmp/a;b.png"; bbox 0 0 600 800; ppageno 0'>
+ <div class='ocr_carea' id='block_1_1' title="bbox 10 10 590 100">
+  <p class='ocr_par' id='par_1_1' lang='eng' title="bbox 10 10 590 100">
+   <span class='ocr_header' id='line_1_1' title="bbox 10 10 590 100; baseline 0.01 -5; x_size 40">
+    <span class='ocrx_word' id='word_1_1' title='bbox 10 10 200 100; x_wconf 91'><strong>Title</strong></span>
+   </span>
+  </p>
+ </div>
+ <div class='ocr_photo' id='block_1_2' title="bbox 10 120 590 500"></div>
+ <div class='ocr_carea' id='block_1_3' title="bbox 10 520 590 790">
+  <p class='ocr_par' id='par_1_2' lang='eng' title="bbox 10 520 590 600">
+   <span class='ocr_caption' id='line_1_2' title="bbox 10 520 590 600; baseline 0 -3; x_size 20">
+    <span class='ocrx_word' id='word_1_2' title='bbox 10 520 100 600; x_wconf 88'><em>Figure&amp;1</em></span>
+   </span>
+  </p>
+  <p class='ocr_par' id='par_1_3' lang='eng' dir='ltr' title="bbox 10 620 590 790">
+   <span class='ocr_line' id='line_1_3' title="bbox 10 620 590 700; baseline 0 -4; x_size 22">
+    <span class='ocrx_word' id='word_1_3' title='bbox 10 620 100 700; x_wconf 95'>foo</span>
+    <span class='ocrx_word' id='word_1_4' title='bbox 110 620 200 700; x_wconf 96'>bar</span>
+   </span>
+   <span class='ocr_textfloat' id='line_1_4' title="bbox 10 710 590 790; baseline 0 -4; x_size 22">
+    <span class='ocrx_word' id='word_1_5' title='bbox 10 710 100 790; x_wconf 97'>baz</span>
+   </span>
+  </p>
+ </div>
+</div>
+</body></html>`))
+		Expect(t, err).ToBe(nil)
+		Expect(t, len(doc.Pages)).ToBe(1)
+		page := doc.Pages[0]
+		img, _ := page.Title.Image()
+		Expect(t, img).ToBe("/tmp/a;b.png")
+		Expect(t, len(page.Areas)).ToBe(3)
+		Expect(t, page.Areas[1].Class).ToBe("ocr_photo")
+		Expect(t, len(page.Areas[2].Paragraphs)).ToBe(2)
+		header := page.Areas[0].Paragraphs[0].Lines[0]
+		Expect(t, header.Class).ToBe("ocr_header")
+		Expect(t, header.Words[0].Text).ToBe("Title")
+		Expect(t, header.Words[0].Bold).ToBe(true)
+		size, _ := header.Title.XSize()
+		Expect(t, size).ToBe(40.0)
+		slope, offset, _ := header.Title.Baseline()
+		Expect(t, slope).ToBe(0.01)
+		Expect(t, offset).ToBe(-5.0)
+		caption := page.Areas[2].Paragraphs[0].Lines[0]
+		Expect(t, caption.Class).ToBe("ocr_caption")
+		Expect(t, caption.Words[0].Text).ToBe("Figure&1")
+		Expect(t, caption.Words[0].Italic).ToBe(true)
+		par := page.Areas[2].Paragraphs[1]
+		Expect(t, par.Dir).ToBe("ltr")
+		Expect(t, len(par.Lines)).ToBe(2)
+		Expect(t, par.Lines[1].Class).ToBe("ocr_textfloat")
+		Expect(t, len(par.Lines[0].Words)).ToBe(2)
+
+		var buf strings.Builder
+		Expect(t, doc.Encode(&buf)).ToBe(nil)
+		again, err := ParseHOCR(strings.NewReader(buf.String()))
+		Expect(t, err).ToBe(nil)
+		Expect(t, again).Deeply().ToBe(doc)
+	})
+}
+
+func TestParseHOCRTitle(t *testing.T) {
+	title, err := ParseHOCRTitle(`image "C:\scans\page; 1.png"; bbox 0 0 10  20;ppageno 3`)
+	Expect(t, err).ToBe(nil)
+	Expect(t, title).ToBe(HOCRTitle{
+		{Name: "image", Values: []string{`"C:\scans\page; 1.png"`}},
+		{Name: "bbox", Values: []string{"0", "0", "10", "20"}},
+		{Name: "ppageno", Values: []string{"3"}},
+	})
+	img, _ := title.Image()
+	Expect(t, img).ToBe(`C:\scans\page; 1.png`)
+	box, _ := title.BBox()
+	Expect(t, box).ToBe(image.Rect(0, 0, 10, 20))
+	Expect(t, title.String()).ToBe(`image "C:\scans\page; 1.png"; bbox 0 0 10 20; ppageno 3`)
+	_, ok := title.XWConf()
+	Expect(t, ok).ToBe(false)
+
+	When(t, "a quote is not terminated", func(t *testing.T) {
+		_, err := ParseHOCRTitle(`image "foo`)
+		Expect(t, err).Not().ToBe(nil)
+	})
+}
+
+func TestClient_ALTOText(t *testing.T) {
+	for _, name := range []string{"001-helloworld.png", "002-confusing.png", "003-longer-text.png"} {
+		client, _ := NewClient()
+		defer client.Close()
+		client.SetImage(filepath.Join("./test/data", name))
+		out, err := client.ALTOText()
+		Expect(t, err).ToBe(nil)
+		checkALTOStructure(t, out)
+
+		text, err := client.Text()
+		Expect(t, err).ToBe(nil)
+		doc := new(altoDocument)
+		Expect(t, xml.Unmarshal([]byte(out), doc)).ToBe(nil)
+		var contents []string
+		for _, block := range doc.Page.PrintSpace.Blocks {
+			for _, textBlock := range block.TextBlocks {
+				for _, line := range textBlock.Lines {
+					for _, s := range line.Strings {
+						if s.XMLName.Local == "String" {
+							contents = append(contents, s.Content)
+						}
+					}
+				}
+			}
+		}
+		Expect(t, strings.Join(contents, " ")).ToBe(strings.Join(strings.Fields(text), " "))
+	}
+
+	When(t, "the page has images and separators", func(t *testing.T) {
+		doc, err := ParseHOCR(strings.NewReader(`<div class='ocr_page' id='page_1' title='bbox 0 0 600 800; ppageno 2'>
+ <div class='ocr_photo' id='block_1_1' title="bbox 10 10 590 300"></div>
+ <div class='ocr_separator' id='block_1_2' title="bbox 10 310 590 312"></div>
+ <div class='ocr_carea' id='block_1_3' title="bbox 10 320 590 400">
+  <p class='ocr_par' id='par_1_1' lang='deu' title="bbox 10 320 590 400">
+   <span class='ocr_caption' id='line_1_1' title="bbox 10 320 590 400; baseline 0 -3; x_size 20">
+    <span class='ocrx_word' id='word_1_1' title='bbox 10 320 100 400; x_wconf 88'><strong><em>Bild</em></strong></span>
+    <span class='ocrx_word' id='word_1_2' title='bbox 120 320 200 400; x_wconf 93'>1</span>
+   </span>
+  </p>
+ </div>
+</div>`))
+		Expect(t, err).ToBe(nil)
+		b, err := xml.Marshal(newALTODocument(doc.Pages[0], "5.3.0"))
+		Expect(t, err).ToBe(nil)
+		checkALTOStructure(t, string(b))
+
+		alto := new(altoDocument)
+		Expect(t, xml.Unmarshal(b, alto)).ToBe(nil)
+		Expect(t, alto.Page.ID).ToBe("page_2")
+		Expect(t, alto.Page.PhysicalImgNr).ToBe(2)
+		blocks := alto.Page.PrintSpace.Blocks
+		Expect(t, len(blocks)).ToBe(3)
+		Expect(t, blocks[0].XMLName.Local).ToBe("Illustration")
+		Expect(t, blocks[1].XMLName.Local).ToBe("GraphicalElement")
+		Expect(t, blocks[2].XMLName.Local).ToBe("ComposedBlock")
+		Expect(t, blocks[2].TextBlocks[0].Lang).ToBe("deu")
+		strs := blocks[2].TextBlocks[0].Lines[0].Strings
+		Expect(t, len(strs)).ToBe(3)
+		Expect(t, strs[0].Content).ToBe("Bild")
+		Expect(t, strs[0].Style).ToBe("bold italics")
+		Expect(t, *strs[0].WC).ToBe(0.88)
+		Expect(t, strs[1].XMLName.Local).ToBe("SP")
+		Expect(t, strs[1].HPos).ToBe(100)
+		Expect(t, strs[1].Width).ToBe(20)
+	})
+}
+
+// checkALTOStructure is a structural check, not a validation against the XSD, which is
+// out of scope. It checks the parts of the ALTO v4 content model which ALTOText writes,
+// transcribed by hand: the allowed children, the required attributes, the non-negative
+// positions, the range of WC and the uniqueness of IDs.
+func checkALTOStructure(t *testing.T, out string) {
+	t.Helper()
+	children := map[string][]string{
+		"alto":               {"Description", "Styles", "Tags", "Layout"},
+		"Description":        {"MeasurementUnit", "sourceImageInformation", "OCRProcessing", "Processing"},
+		"MeasurementUnit":    {},
+		"Processing":         {"processingDateTime", "processingAgency", "processingStepDescription", "processingStepSettings", "processingSoftware"},
+		"processingSoftware": {"softwareCreator", "softwareName", "softwareVersion", "applicationDescription"},
+		"softwareName":       {},
+		"softwareVersion":    {},
+		"Layout":             {"Page"},
+		"Page":               {"TopMargin", "LeftMargin", "RightMargin", "BottomMargin", "PrintSpace"},
+		"PrintSpace":         {"TextBlock", "Illustration", "GraphicalElement", "ComposedBlock"},
+		"ComposedBlock":      {"Shape", "TextBlock", "Illustration", "GraphicalElement", "ComposedBlock"},
+		"Illustration":       {"Shape"},
+		"GraphicalElement":   {"Shape"},
+		"TextBlock":          {"Shape", "TextLine"},
+		"TextLine":           {"Shape", "String", "SP", "HYP"},
+		"String":             {"ALTERNATIVE", "Glyph"},
+		"SP":                 {},
+	}
+	block := []string{"ID", "HPOS", "VPOS", "WIDTH", "HEIGHT"}
+	required := map[string][]string{
+		"Processing":       {"ID"},
+		"Page":             {"ID", "PHYSICAL_IMG_NR"},
+		"ComposedBlock":    block,
+		"Illustration":     block,
+		"GraphicalElement": block,
+		"TextBlock":        block,
+		"TextLine":         block,
+		"String":           {"CONTENT", "HPOS", "VPOS", "WIDTH", "HEIGHT"},
+		"SP":               {"HPOS", "VPOS", "WIDTH"},
+	}
+	floats := map[string]bool{"HPOS": true, "VPOS": true, "WIDTH": true, "HEIGHT": true, "PHYSICAL_IMG_NR": true}
+
+	decoder := xml.NewDecoder(strings.NewReader(out))
+	ids := map[string]bool{}
+	var parents []string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		Expect(t, err).ToBe(nil)
+		switch token := token.(type) {
+		case xml.StartElement:
+			name := token.Name.Local
+			if token.Name.Space != altoNamespace {
+				t.Errorf("%s is not in the namespace of ALTO v4", name)
+			}
+			if len(parents) == 0 {
+				Expect(t, name).ToBe("alto")
+			} else if parent := parents[len(parents)-1]; !contains(children[parent], name) {
+				t.Errorf("%s is not allowed within %s", name, parent)
+			}
+			attrs := map[string]string{}
+			for _, attr := range token.Attr {
+				attrs[attr.Name.Local] = attr.Value
+			}
+			for _, attr := range required[name] {
+				if _, ok := attrs[attr]; !ok {
+					t.Errorf("%s of %s is required", attr, name)
+				}
+			}
+			for attr, value := range attrs {
+				if floats[attr] {
+					if f, err := strconv.ParseFloat(value, 64); err != nil || f < 0 {
+						t.Errorf("%s of %s is not a position: %q", attr, name, value)
+					}
+				}
+			}
+			if wc, ok := attrs["WC"]; ok {
+				if f, err := strconv.ParseFloat(wc, 64); err != nil || f < 0 || f > 1 {
+					t.Errorf("WC of %s is not between 0 and 1: %q", name, wc)
+				}
+			}
+			if id, ok := attrs["ID"]; ok {
+				if ids[id] {
+					t.Errorf("ID %s is not unique", id)
+				}
+				ids[id] = true
+			}
+			parents = append(parents, name)
+		case xml.EndElement:
+			parents = parents[:len(parents)-1]
+		}
+	}
+	Expect(t, len(parents)).ToBe(0)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestClient_WritePDF(t *testing.T) {
+	client, _ := NewClient()
+	defer client.Close()
+	client.SetImage("./test/data/001-helloworld.png")
+	var buf bytes.Buffer
+	err := client.WritePDF(&buf, PDFOptions{Title: "Hello"})
+	Expect(t, err).ToBe(nil)
+	objects := checkPDF(t, buf.Bytes())
+
+	page := findPDFObject(objects, "/Type /Page ")
+	// 1174x236 pixels at 144 DPI.
+	Expect(t, page).Match(`/MediaBox \[0 0 587 118\]`)
+	Expect(t, page).Match(`/XObject`)
+	Expect(t, findPDFObject(objects, "/Subtype /Image")).Match(`/Width 1174 /Height 236 /ColorSpace /DeviceRGB /BitsPerComponent 8`)
+	Expect(t, findPDFObject(objects, "/Type /Pages")).Match(`/Count 1`)
+	Expect(t, findPDFObject(objects, "/Producer")).Match(`/Title <FEFF00480065006C006C006F>`)
+
+	content := pdfStream(t, objects[pdfObjectNumber(t, page, "/Contents")])
+	Expect(t, content).Match(`/Im0 Do`)
+	Expect(t, content).Match(`3 Tr`)
+	// "Hello, " and "World!" as UTF-16 code units.
+	Expect(t, content).Match(`<00480065006C006C006F002C0020> Tj`)
+	Expect(t, content).Match(`<0057006F0072006C00640021> Tj`)
+
+	When(t, "text only is given", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := client.WritePDF(&buf, PDFOptions{TextOnly: true})
+		Expect(t, err).ToBe(nil)
+		objects := checkPDF(t, buf.Bytes())
+		page := findPDFObject(objects, "/Type /Page ")
+		Expect(t, page).Not().Match(`/XObject`)
+		Expect(t, findPDFObject(objects, "/Subtype /Image")).ToBe("")
+		content := pdfStream(t, objects[pdfObjectNumber(t, page, "/Contents")])
+		Expect(t, content).Not().Match(`/Im0 Do`)
+		Expect(t, content).Match(`<0057006F0072006C00640021> Tj`)
+	})
+
+	When(t, "many images are added to PDFWriter", func(t *testing.T) {
+		var buf bytes.Buffer
+		pdf := NewPDFWriter(&buf, PDFOptions{})
+		for _, name := range []string{"001-helloworld.png", "003-longer-text.png"} {
+			client.SetImage(filepath.Join("./test/data", name))
+			Expect(t, pdf.AddPage(client)).ToBe(nil)
+		}
+		Expect(t, pdf.Close()).ToBe(nil)
+		Expect(t, pdf.AddPage(client)).Not().ToBe(nil)
+		objects := checkPDF(t, buf.Bytes())
+		Expect(t, findPDFObject(objects, "/Type /Pages")).Match(`/Count 2`)
+	})
+
+	When(t, "the image is gray", func(t *testing.T) {
+		img := image.NewGray(image.Rect(0, 0, 100, 50))
+		for i := range img.Pix {
+			img.Pix[i] = 0xff
+		}
+		client.SetImageFromImage(img)
+		var buf bytes.Buffer
+		err := client.WritePDF(&buf, PDFOptions{})
+		Expect(t, err).ToBe(nil)
+		objects := checkPDF(t, buf.Bytes())
+		image := findPDFObject(objects, "/Subtype /Image")
+		Expect(t, image).Match(`/Width 100 /Height 50 /ColorSpace /DeviceGray /BitsPerComponent 8`)
+		data := pdfStream(t, image)
+		Expect(t, len(data)).ToBe(100 * 50)
+		Expect(t, strings.Trim(data, "\xff")).ToBe("")
+	})
+
+	When(t, "a rectangle is set", func(t *testing.T) {
+		client.SetImage("./test/data/003-longer-text.png")
+		Expect(t, client.SetRectangle(image.Rect(70, 40, 340, 80))).ToBe(nil)
+		defer client.ClearRectangle()
+		var buf bytes.Buffer
+		err := client.WritePDF(&buf, PDFOptions{})
+		Require(t, err).ToBe(nil)
+		objects := checkPDF(t, buf.Bytes())
+		Because(t, "the page and the image are the rectangle", func(t *testing.T) {
+			Expect(t, findPDFObject(objects, "/Subtype /Image")).Match(`/Width 270 /Height 40 `)
+			page := findPDFObject(objects, "/Type /Page ")
+			content := pdfStream(t, objects[pdfObjectNumber(t, page, "/Contents")])
+			Expect(t, content).Match(`/Im0 Do`)
+			Expect(t, content).Match(`<00570072006900740069006E00670020> Tj`)
+		})
+	})
+}
+
+func TestPDFWriter_writePage(t *testing.T) {
+	// A page of the rectangle (2,1)-(7,3) of an image of 8x4 pixels, as HOCRText gives it.
+	doc, err := ParseHOCR(strings.NewReader(`<div class='ocr_page' id='page_1' title='bbox 2 1 7 3; scan_res 72 72'></div>`))
+	Expect(t, err).ToBe(nil)
+	for _, raster := range []*pixRaster{
+		{width: 8, height: 4, depth: 1, data: []byte{0x00, 0x3c, 0x24, 0x00}},
+		{width: 8, height: 4, depth: 8, data: []byte(
+			"\x00\x00\x00\x00\x00\x00\x00\x00" +
+				"\x00\x00\xff\xff\xff\xff\x00\x00" +
+				"\x00\x00\xff\x00\x00\xff\x00\x00" +
+				"\x00\x00\x00\x00\x00\x00\x00\x00")},
+	} {
+		var buf bytes.Buffer
+		pdf := NewPDFWriter(&buf, PDFOptions{})
+		pdf.writePage(doc.Pages[0], raster)
+		Expect(t, pdf.Close()).ToBe(nil)
+		objects := checkPDF(t, buf.Bytes())
+
+		page := findPDFObject(objects, "/Type /Page ")
+		Expect(t, page).Match(`/MediaBox \[0 0 5 2\]`)
+		content := pdfStream(t, objects[pdfObjectNumber(t, page, "/Contents")])
+		Expect(t, content).Match(`q 5 0 0 2 0 0 cm /Im0 Do Q`)
+		image := findPDFObject(objects, "/Subtype /Image")
+		Expect(t, image).Match(fmt.Sprintf(`/Width 5 /Height 2 /ColorSpace /DeviceGray /BitsPerComponent %d`, raster.depth))
+		if raster.depth == 1 {
+			Expect(t, []byte(pdfStream(t, image))).ToBe([]byte{0xf0, 0x90})
+		} else {
+			Expect(t, pdfStream(t, image)).ToBe("\xff\xff\xff\xff\x00\xff\x00\x00\xff\x00")
+		}
+
+		descriptor := findPDFObject(objects, "/Type /FontDescriptor")
+		Expect(t, pdfStream(t, objects[pdfObjectNumber(t, descriptor, "/FontFile2")])).ToBe(string(glyphLessFont))
+		cidFont := findPDFObject(objects, "/Subtype /CIDFontType2")
+		Expect(t, pdfStream(t, objects[pdfObjectNumber(t, cidFont, "/CIDToGIDMap")])).ToBe(strings.Repeat("\x00\x01", 1<<16))
+	}
+
+	When(t, "no page was added", func(t *testing.T) {
+		var buf bytes.Buffer
+		pdf := NewPDFWriter(&buf, PDFOptions{})
+		Expect(t, pdf.Close()).Not().ToBe(nil)
+		Expect(t, buf.Len()).ToBe(0)
+	})
+}
+
+func TestGlyphLessFont(t *testing.T) {
+	font := glyphLessFont
+	u16 := func(b []byte) int { return int(b[0])<<8 | int(b[1]) }
+	u32 := func(b []byte) uint32 { return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3]) }
+	checksum := func(b []byte) uint32 {
+		var sum uint32
+		for len(b)%4 != 0 {
+			b = append(b[:len(b):len(b)], 0)
+		}
+		for i := 0; i < len(b); i += 4 {
+			sum += u32(b[i:])
+		}
+		return sum
+	}
+	Expect(t, u32(font)).ToBe(uint32(0x10000))
+	Expect(t, checksum(font)).ToBe(uint32(0xb1b0afba))
+
+	tables := map[string][]byte{}
+	previous := ""
+	for i := 0; i < u16(font[4:]); i++ {
+		entry := font[12+16*i:]
+		tag := string(entry[:4])
+		Expect(t, tag > previous).ToBe(true)
+		previous = tag
+		offset, length := u32(entry[8:]), u32(entry[12:])
+		Expect(t, offset%4).ToBe(uint32(0))
+		Require(t, int(offset+length) <= len(font)).ToBe(true)
+		tables[tag] = font[offset : offset+length]
+		if tag != "head" {
+			Expect(t, checksum(tables[tag])).ToBe(u32(entry[4:]))
+		}
+	}
+	for _, tag := range []string{"cmap", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "post"} {
+		_, ok := tables[tag]
+		Expect(t, ok).ToBe(true)
+	}
+	Expect(t, len(tables["head"])).ToBe(54)
+	Expect(t, u32(tables["head"][12:])).ToBe(uint32(0x5f0f3cf5))
+	Expect(t, u16(tables["head"][18:])).ToBe(glyphLessUnitsPerEm)
+	numGlyphs := u16(tables["maxp"][4:])
+	Expect(t, numGlyphs).ToBe(2)
+	Expect(t, u16(tables["hhea"][34:])).ToBe(numGlyphs)
+	Expect(t, len(tables["hmtx"])).ToBe(4 * numGlyphs)
+	Expect(t, u16(tables["hmtx"][4:])).ToBe(glyphLessWidth)
+	// Short offsets, as indexToLocFormat of head is 0.
+	Expect(t, u16(tables["head"][50:])).ToBe(0)
+	Expect(t, len(tables["loca"])).ToBe(2 * (numGlyphs + 1))
+}
+
+// checkPDF checks the structure of the PDF in b, every object of the cross reference table must
+// be at its offset. It returns the objects by their numbers.
+func checkPDF(t *testing.T, b []byte) map[int]string {
+	t.Helper()
+	Expect(t, bytes.HasPrefix(b, []byte("%PDF-1.5\n"))).ToBe(true)
+	Expect(t, bytes.HasSuffix(b, []byte("%%EOF\n"))).ToBe(true)
+	i := bytes.LastIndex(b, []byte("startxref\n"))
+	if i < 0 {
+		t.Fatalf("startxref is missing")
+	}
+	xref, err := strconv.Atoi(strings.Fields(string(b[i+len("startxref\n"):]))[0])
+	Expect(t, err).ToBe(nil)
+	Expect(t, bytes.HasPrefix(b[xref:], []byte("xref\n0 "))).ToBe(true)
+	lines := strings.Split(string(b[xref:]), "\n")
+	size, err := strconv.Atoi(strings.Fields(lines[1])[1])
+	Expect(t, err).ToBe(nil)
+	Expect(t, string(b)).Match(fmt.Sprintf(`/Size %d /Root 1 0 R`, size))
+
+	objects := map[int]string{}
+	for n := 1; n < size; n++ {
+		offset, err := strconv.Atoi(strings.Fields(lines[2+n])[0])
+		Expect(t, err).ToBe(nil)
+		prefix := fmt.Sprintf("%d 0 obj\n", n)
+		if !bytes.HasPrefix(b[offset:], []byte(prefix)) {
+			t.Fatalf("object %d is not at %d", n, offset)
+		}
+		end := bytes.Index(b[offset:], []byte("\nendobj\n"))
+		Expect(t, end > 0).ToBe(true)
+		objects[n] = string(b[offset+len(prefix) : offset+end])
+	}
+	return objects
+}
+
+func findPDFObject(objects map[int]string, substr string) string {
+	for _, object := range objects {
+		if strings.Contains(object, substr) {
+			return object
+		}
+	}
+	return ""
+}
+
+// pdfObjectNumber returns the number of the object, which key of object refers to.
+func pdfObjectNumber(t *testing.T, object, key string) int {
+	t.Helper()
+	fields := strings.Fields(object[strings.Index(object, key)+len(key):])
+	n, err := strconv.Atoi(fields[0])
+	Expect(t, err).ToBe(nil)
+	return n
+}
+
+// pdfStream returns the data of the stream object, decompressed if it's compressed.
+func pdfStream(t *testing.T, object string) string {
+	t.Helper()
+	start := strings.Index(object, "\nstream\n") + len("\nstream\n")
+	end := strings.LastIndex(object, "\nendstream")
+	Expect(t, strings.Contains(object, fmt.Sprintf("/Length %d ", end-start))).ToBe(true)
+	data := object[start:end]
+	if strings.Contains(object, "/FlateDecode") {
+		r, err := zlib.NewReader(strings.NewReader(data))
+		Expect(t, err).ToBe(nil)
+		b, err := io.ReadAll(r)
+		Expect(t, err).ToBe(nil)
+		data = string(b)
+	}
+	return data
+}
+
+func TestClient_TSVText(t *testing.T) {
+	client, _ := NewClient()
+	defer client.Close()
+	client.SetImage("./test/data/001-helloworld.png")
+	out, err := client.TSVText(0)
+	Require(t, err).ToBe(nil)
+	rows, err := ParseTSV(strings.NewReader(out))
+	Expect(t, err).ToBe(nil)
+	Expect(t, rows[0].Level).ToBe(TSVLevelPage)
+	Expect(t, rows[0].Box).ToBe(image.Rect(0, 0, 1174, 236))
+	var words []string
+	for _, row := range rows {
+		Expect(t, row.PageNum).ToBe(1)
+		if row.Level == TSVLevelWord {
+			words = append(words, row.Text)
+		} else {
+			Expect(t, row.Conf).ToBe(-1.0)
+			Expect(t, row.Text).ToBe("")
+		}
+	}
+	Expect(t, words).ToBe([]string{"Hello,", "World!"})
+
+	When(t, "another page number is given", func(t *testing.T) {
+		out, err := client.TSVText(1)
+		Expect(t, err).ToBe(nil)
+		rows, err := ParseTSV(strings.NewReader(out))
+		Expect(t, err).ToBe(nil)
+		Expect(t, rows[0].PageNum).ToBe(2)
+		_, err = client.TSVText(-1)
+		Expect(t, err).Not().ToBe(nil)
+	})
+
+	Because(t, "it has the columns of the tsv config of the tesseract command line", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+		Expect(t, len(lines)).ToBe(len(rows))
+		for i, line := range lines {
+			fields := strings.Split(line, "\t")
+			Expect(t, len(fields)).ToBe(12)
+			if rows[i].Level != TSVLevelWord {
+				Expect(t, fields[10:]).ToBe([]string{"-1", ""})
+			}
+		}
+	})
+}
+
+func TestParseTSV(t *testing.T) {
+	rows, err := ParseTSV(strings.NewReader(TSVHeader +
+		"1\t1\t0\t0\t0\t0\t0\t0\t1174\t236\t-1\t\n" +
+		"2\t1\t1\t0\t0\t0\t74\t64\t1025\t126\t-1\t\n" +
+		"3\t1\t1\t1\t0\t0\t74\t64\t1025\t126\t-1\t\n" +
+		"4\t1\t1\t1\t1\t0\t74\t64\t1025\t126\t-1\t\n" +
+		"5\t1\t1\t1\t1\t1\t74\t64\t450\t126\t96.418465\tHello,\n" +
+		"5\t1\t1\t1\t1\t2\t638\t64\t461\t106\t91.090385\tWorld!\n"))
+	Expect(t, err).ToBe(nil)
+	Expect(t, len(rows)).ToBe(6)
+	Expect(t, rows[3]).ToBe(TSVRow{Level: TSVLevelLine, PageNum: 1, BlockNum: 1, ParNum: 1, LineNum: 1, Box: image.Rect(74, 64, 1099, 190), Conf: -1})
+	Expect(t, rows[5]).ToBe(TSVRow{Level: TSVLevelWord, PageNum: 1, BlockNum: 1, ParNum: 1, LineNum: 1, WordNum: 2, Box: image.Rect(638, 64, 1099, 170), Conf: 91.090385, Text: "World!"})
+
+	When(t, "a row is broken", func(t *testing.T) {
+		_, err := ParseTSV(strings.NewReader("5\t1\t1\t1\t1\t1\t74\t64\t450\t126\t96.4\n"))
+		Expect(t, err).Not().ToBe(nil)
+		_, err = ParseTSV(strings.NewReader("5\t1\t1\t1\t1\t1\t74\t64\t450\tfoo\t96.4\tHello,\n"))
+		Expect(t, err).Not().ToBe(nil)
+	})
+}
+
+func TestClient_BoxText(t *testing.T) {
+	client, _ := NewClient()
+	defer client.Close()
+	client.SetImage("./test/data/001-helloworld.png")
+	out, err := client.BoxText()
+	Require(t, err).ToBe(nil)
+	lines, err := ParseBoxText(strings.NewReader(out))
+	Expect(t, err).ToBe(nil)
+	Expect(t, FormatBoxText(lines)).ToBe(out)
+	var text string
+	for _, line := range lines {
+		text += line.Text
+		Expect(t, line.Page).ToBe(0)
+		// The origin is at the bottom left corner of the 1174x236 image.
+		Expect(t, line.Box.In(image.Rect(0, 0, 1174, 236))).ToBe(true)
+	}
+	Expect(t, text).ToBe("Hello,World!")
+
+	Because(t, "the origin is at the bottom left corner, where the one of GetBoundingBoxes is at the top left", func(t *testing.T) {
+		symbols, err := client.GetBoundingBoxes(RIL_SYMBOL)
+		Require(t, err).ToBe(nil)
+		Expect(t, symbols[0].Word).ToBe("H")
+		Expect(t, lines[0].Text).ToBe("H")
+		h := symbols[0].Box
+		Expect(t, lines[0].Box).ToBe(image.Rect(h.Min.X, 236-h.Max.Y, h.Max.X, 236-h.Min.Y))
+		Expect(t, lines[0].Box.Min.Y > 0).ToBe(true)
+	})
+
+	When(t, "the client is closed", func(t *testing.T) {
+		client, _ := NewClient()
+		client.Close()
+		_, err := client.BoxText()
+		Expect(t, errors.Is(err, ErrClientClosed)).ToBe(true)
+	})
+
+	When(t, "LSTM box text is requested", func(t *testing.T) {
+		out, err := client.LSTMBoxText()
+		Expect(t, err).ToBe(nil)
+		lines, err := ParseBoxText(strings.NewReader(out))
+		Expect(t, err).ToBe(nil)
+		Expect(t, FormatBoxText(lines)).ToBe(out)
+		var text string
+		for _, line := range lines {
+			text += line.Text
+		}
+		Expect(t, text).ToBe("Hello, World!\t")
+	})
+
+	When(t, "WordStr box text is requested", func(t *testing.T) {
+		out, err := client.WordStrBoxText()
+		Expect(t, err).ToBe(nil)
+		lines, err := ParseWordStrBoxText(strings.NewReader(out))
+		Expect(t, err).ToBe(nil)
+		Expect(t, FormatWordStrBoxText(lines)).ToBe(out)
+		Expect(t, len(lines)).ToBe(2)
+		Expect(t, lines[0].Text).ToBe("Hello, World!")
+		Expect(t, lines[1].Text).ToBe("\t")
+	})
+}
+
+func TestParseBoxText(t *testing.T) {
+	in := "H 74 68 138 168 0\n" +
+		"e 150 68 200 140 0\n" +
+		"  200 68 638 172 0\n" +
+		"W 638 70 750 170 0\n" +
+		"\t 1100 66 1104 172 0\n"
+	lines, err := ParseBoxText(strings.NewReader(in))
+	Expect(t, err).ToBe(nil)
+	Expect(t, len(lines)).ToBe(5)
+	Expect(t, lines[0]).ToBe(BoxLine{Text: "H", Box: image.Rect(74, 68, 138, 168)})
+	Expect(t, lines[2].Text).ToBe(" ")
+	Expect(t, lines[4].Text).ToBe("\t")
+	Expect(t, FormatBoxText(lines)).ToBe(in)
+
+	When(t, "ground truth is edited", func(t *testing.T) {
+		lines[1].Text = "é"
+		lines[1].Page = 3
+		Expect(t, FormatBoxText(lines[:2])).ToBe("H 74 68 138 168 0\né 150 68 200 140 3\n")
+	})
+
+	When(t, "a line is broken", func(t *testing.T) {
+		_, err := ParseBoxText(strings.NewReader("H 74 68 138 168\n"))
+		Expect(t, err).Not().ToBe(nil)
+		_, err = ParseBoxText(strings.NewReader("H 74 68 138 top 0\n"))
+		Expect(t, err).Not().ToBe(nil)
+	})
+}
+
+func TestParseWordStrBoxText(t *testing.T) {
+	in := "WordStr 74 46 1099 172 0 #Hello, World!\n" +
+		"\t 1100 46 1104 172 0\n" +
+		"WordStr 74 10 300 40 0 #second line\n" +
+		"\t 301 10 305 40 0\n"
+	lines, err := ParseWordStrBoxText(strings.NewReader(in))
+	Expect(t, err).ToBe(nil)
+	Expect(t, len(lines)).ToBe(4)
+	Expect(t, lines[0]).ToBe(BoxLine{Text: "Hello, World!", Box: image.Rect(74, 46, 1099, 172)})
+	Expect(t, lines[1]).ToBe(BoxLine{Text: "\t", Box: image.Rect(1100, 46, 1104, 172)})
+	Expect(t, lines[2].Text).ToBe("second line")
+	Expect(t, FormatWordStrBoxText(lines)).ToBe(in)
+
+	When(t, "the text is missing", func(t *testing.T) {
+		_, err := ParseWordStrBoxText(strings.NewReader("WordStr 74 46 1099 172 0 Hello\n"))
+		Expect(t, err).Not().ToBe(nil)
+	})
+}
+
 func TestGetAvailableLangs(t *testing.T) {
 	t.Skip("TODO")
 	// langs, err := GetAvailableLanguages()
 	// Expect(t, err).ToBe(nil)
 	// Expect(t, len(langs)).ToBe(1) // eng only
 }
+
+func TestPool(t *testing.T) {
+	pool, err := NewPool(1, WithPageSegMode(PSM_SINGLE_BLOCK))
+	Expect(t, err).ToBe(nil)
+	defer pool.Close()
+
+	client, err := pool.Acquire(context.Background())
+	Expect(t, err).ToBe(nil)
+	client.SetImage("./test/data/001-helloworld.png")
+	client.SetWhitelist("H")
+	_, err = client.Text()
+	Expect(t, err).ToBe(nil)
+
+	When(t, "all clients are in use", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := pool.Acquire(ctx)
+		Expect(t, err).ToBe(context.DeadlineExceeded)
+		Because(t, "other languages are kept apart", func(t *testing.T) {
+			_, err := pool.Acquire(ctx, WithLanguages("undefined-language"))
+			Expect(t, err).Not().ToBe(nil)
+		})
+	})
+
+	pool.Release(client)
+
+	Because(t, "the image and variables are reset on release", func(t *testing.T) {
+		reused, err := pool.Acquire(context.Background())
+		Expect(t, err).ToBe(nil)
+		Expect(t, reused).ToBe(client)
+		_, err = reused.Text()
+		Expect(t, err).Not().ToBe(nil)
+		reused.SetImage("./test/data/001-helloworld.png")
+		text, err := reused.Text()
+		Expect(t, err).ToBe(nil)
+		Expect(t, text).ToBe("Hello, World!")
+		pool.Release(reused)
+	})
+
+	Because(t, "the config file is reset on release", func(t *testing.T) {
+		configured, err := pool.Acquire(context.Background())
+		Expect(t, err).ToBe(nil)
+		Expect(t, configured.SetConfigFile("./test/config/01.config")).ToBe(nil)
+		configured.SetImage("./test/data/001-helloworld.png")
+		_, err = configured.Text()
+		Expect(t, err).ToBe(nil)
+		pool.Release(configured)
+
+		reused, err := pool.Acquire(context.Background())
+		Expect(t, err).ToBe(nil)
+		Expect(t, reused).ToBe(configured)
+		Expect(t, reused.ConfigFilePath).ToBe("")
+		reused.SetImage("./test/data/001-helloworld.png")
+		text, err := reused.Text()
+		Expect(t, err).ToBe(nil)
+		Expect(t, text).ToBe("Hello, World!")
+		pool.Release(reused)
+	})
+
+	Because(t, "options which are not part of the key are applied on every Acquire", func(t *testing.T) {
+		untrimmed, err := pool.Acquire(context.Background(), WithTrim(false))
+		Expect(t, err).ToBe(nil)
+		Expect(t, untrimmed).ToBe(client)
+		Expect(t, untrimmed.Trim).ToBe(false)
+		pool.Release(untrimmed)
+		trimmed, err := pool.Acquire(context.Background())
+		Expect(t, err).ToBe(nil)
+		Expect(t, trimmed.Trim).ToBe(true)
+		pool.Release(trimmed)
+	})
+
+	When(t, "clients of other keys are idle", func(t *testing.T) {
+		pool, err := NewPool(1)
+		Expect(t, err).ToBe(nil)
+		defer pool.Close()
+		client, err := pool.Acquire(context.Background())
+		Expect(t, err).ToBe(nil)
+		pool.Release(client)
+		Because(t, "the idle client is closed to keep the pool at its size", func(t *testing.T) {
+			other, err := pool.Acquire(context.Background(), WithPageSegMode(PSM_SINGLE_LINE))
+			Expect(t, err).ToBe(nil)
+			Expect(t, client.wasm == nil).ToBe(true)
+			Expect(t, pool.alive).ToBe(1)
+			Expect(t, len(pool.clients)).ToBe(1)
+			pool.Release(other)
+		})
+	})
+
+	When(t, "the pool is closed while a client is in use", func(t *testing.T) {
+		pool, err := NewPool(1)
+		Expect(t, err).ToBe(nil)
+		client, err := pool.Acquire(context.Background())
+		Expect(t, err).ToBe(nil)
+		pool.Close()
+		pool.Release(client)
+		Expect(t, client.wasm == nil).ToBe(true)
+		Expect(t, len(pool.clients)).ToBe(0)
+	})
+
+	When(t, "a client trapped", func(t *testing.T) {
+		trapped, _ := pool.Acquire(context.Background())
+		trapped.wasm.err = &WasmError{Func: "UTF8Text", Kind: ErrWasmTrap}
+		pool.Release(trapped)
+		replaced, err := pool.Acquire(context.Background())
+		Expect(t, err).ToBe(nil)
+		Expect(t, replaced == trapped).ToBe(false)
+		pool.Release(replaced)
+	})
+}
+
+func TestCompilationCacheDir(t *testing.T) {
+	if os.Getenv("GOSSERACT_TEST_CACHE_CHILD") == "1" {
+		// Run by the parent test as a fresh process, compiling happens in TestMain already.
+		client, err := NewClient()
+		Expect(t, err).ToBe(nil)
+		client.Close()
+		return
+	}
+
+	err := SetCompilationCacheDir(t.TempDir())
+	Expect(t, err).Not().ToBe(nil)
+
+	dir := t.TempDir()
+	start := func() map[string]time.Time {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestCompilationCacheDir$")
+		cmd.Env = append(os.Environ(), "GOSSERACT_TEST_CACHE_CHILD=1", CompilationCacheDirEnv+"="+dir)
+		started := time.Now()
+		out, err := cmd.CombinedOutput()
+		Expect(t, err).ToBe(nil)
+		t.Logf("process took %v: %s", time.Since(started), out)
+
+		files := map[string]time.Time{}
+		filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				files[path] = info.ModTime()
+			}
+			return err
+		})
+		return files
+	}
+
+	first := start()
+	if len(first) == 0 {
+		t.Skip("the wazero compiler is not supported on this platform, so nothing is cached")
+	}
+	Because(t, "the second process loads the compiled module instead of compiling it again", func(t *testing.T) {
+		Expect(t, start()).ToBe(first)
+	})
+}
+
+func TestEngine(t *testing.T) {
+	engine, err := NewEngine(EngineConfig{})
+	Expect(t, err).ToBe(nil)
+
+	client, err := engine.NewClient(WithPageSegMode(PSM_SINGLE_BLOCK))
+	Expect(t, err).ToBe(nil)
+	client.SetImage("./test/data/001-helloworld.png")
+	text, err := client.Text()
+	Expect(t, err).ToBe(nil)
+	Expect(t, text).ToBe("Hello, World!")
+
+	limited, err := engine.NewClient(WithMemoryLimit(512 * 1024 * 1024))
+	Expect(t, err).ToBe(nil)
+	limited.SetImage("./test/data/001-helloworld.png")
+	closed, err := engine.NewClient(WithMemoryLimit(512 * 1024 * 1024))
+	Expect(t, err).ToBe(nil)
+	Expect(t, closed.Close()).ToBe(nil)
+	Expect(t, len(engine.clientRuntimes)).ToBe(1)
+
+	Expect(t, engine.Close()).ToBe(nil)
+
+	Because(t, "clients are closed along with their engine", func(t *testing.T) {
+		_, err := client.Text()
+		Expect(t, err).Not().ToBe(nil)
+		_, err = engine.NewClient()
+		Expect(t, err).Not().ToBe(nil)
+		_, err = engine.NewClient(WithMemoryLimit(512 * 1024 * 1024))
+		Expect(t, err).Not().ToBe(nil)
+		Expect(t, len(engine.clientRuntimes)).ToBe(0)
+		Because(t, "clients with a memory limit have a runtime of their own", func(t *testing.T) {
+			_, err := limited.Text()
+			Expect(t, err).Not().ToBe(nil)
+			limited.Close()
+		})
+	})
+
+	When(t, "a custom runtime is given", func(t *testing.T) {
+		runtime := wazero.NewRuntime(context.Background())
+		defer runtime.Close(context.Background())
+		engine, err := NewEngine(EngineConfig{Runtime: runtime})
+		Expect(t, err).ToBe(nil)
+		client, err := engine.NewClient()
+		Expect(t, err).ToBe(nil)
+		defer client.Close()
+		Expect(t, engine.Close()).ToBe(nil)
+		Because(t, "the runtime is owned by the caller", func(t *testing.T) {
+			Expect(t, runtime.Module(wasi_snapshot_preview1.ModuleName)).Not().ToBe(nil)
+		})
+	})
+}
+
+func TestClient_SetImageFromImage(t *testing.T) {
+	file, err := os.Open("./test/data/001-helloworld.png")
+	Expect(t, err).ToBe(nil)
+	decoded, err := png.Decode(file)
+	file.Close()
+	Expect(t, err).ToBe(nil)
+
+	bounds := decoded.Bounds()
+	gray := image.NewGray(bounds)
+	rgba := image.NewRGBA(bounds)
+	nrgba := image.NewNRGBA(bounds)
+	paletted := image.NewPaletted(bounds, color.Palette{color.White, color.Black, color.RGBA{R: 0xff, A: 0xff}})
+	draw.Draw(gray, bounds, decoded, bounds.Min, draw.Src)
+	draw.Draw(rgba, bounds, decoded, bounds.Min, draw.Src)
+	draw.Draw(nrgba, bounds, decoded, bounds.Min, draw.Src)
+	draw.Draw(paletted, bounds, decoded, bounds.Min, draw.Src)
+
+	client, _ := NewClient()
+	defer client.Close()
+	client.SetPageSegMode(PSM_SINGLE_BLOCK)
+
+	for _, img := range []image.Image{gray, rgba, nrgba, paletted, gray.SubImage(image.Rect(40, 40, 1200, 220))} {
+		err := client.SetImageFromImage(img)
+		Expect(t, err).ToBe(nil)
+		text, err := client.Text()
+		Expect(t, err).ToBe(nil)
+		Expect(t, text).ToBe("Hello, World!")
+	}
+
+	err = client.SetImageFromImage(image.NewGray(image.Rect(0, 0, 0, 0)))
+	Expect(t, err).Not().ToBe(nil)
+
+	Because(t, "api must be initialized beforehand", func(t *testing.T) {
+		client := &Client{}
+		err := client.SetImageFromImage(gray)
+		Expect(t, err).Not().ToBe(nil)
+	})
+}
+
+func TestClient_SetImageFromReader(t *testing.T) {
+	client, _ := NewClient()
+	defer client.Close()
+
+	file, err := os.Open("./test/data/001-helloworld.png")
+	Expect(t, err).ToBe(nil)
+	defer file.Close()
+
+	err = client.SetImageFromReader(iotest.HalfReader(file), 1024*1024)
+	Expect(t, err).ToBe(nil)
+	client.SetPageSegMode(PSM_SINGLE_BLOCK)
+	text, err := client.Text()
+	Expect(t, err).ToBe(nil)
+	Expect(t, text).ToBe("Hello, World!")
+
+	When(t, "the data exceeds the limit", func(t *testing.T) {
+		file.Seek(0, io.SeekStart)
+		err := client.SetImageFromReader(file, 1024)
+		Expect(t, errors.Is(err, ErrImageTooLarge)).ToBe(true)
+		Because(t, "the image set before is kept", func(t *testing.T) {
+			text, err := client.Text()
+			Expect(t, err).ToBe(nil)
+			Expect(t, text).ToBe("Hello, World!")
+		})
+	})
+
+	When(t, "the format is not supported", func(t *testing.T) {
+		err := client.SetImageFromReader(strings.NewReader("GIF89a\x01\x00\x01\x00"), 1024)
+		Expect(t, errors.Is(err, ErrUnsupportedImageFormat)).ToBe(true)
+		err = client.SetImageFromReader(strings.NewReader(""), 1024)
+		Expect(t, err).Not().ToBe(nil)
+	})
+}
+
+func TestClient_SetImage_DecodeError(t *testing.T) {
+	client, _ := NewClient()
+	defer client.Close()
+
+	content, err := ioutil.ReadFile("./test/data/001-helloworld.png")
+	Expect(t, err).ToBe(nil)
+
+	When(t, "the format is not supported", func(t *testing.T) {
+		err := client.SetImageFromBytes([]byte("GIF89a\x01\x00\x01\x00\x00\x00\x00"))
+		Expect(t, errors.Is(err, ErrUnsupportedImageFormat)).ToBe(true)
+		Expect(t, err.Error()).Match("GIF")
+		err = client.SetImage("./test/config/01.config")
+		Expect(t, errors.Is(err, ErrUnsupportedImageFormat)).ToBe(true)
+		Because(t, "the data never reached leptonica", func(t *testing.T) {
+			client.SetImageFromBytes(content)
+			text, err := client.Text()
+			Expect(t, err).ToBe(nil)
+			Expect(t, text).ToBe("Hello, World!")
+		})
+	})
+
+	When(t, "the data is corrupted", func(t *testing.T) {
+		err := client.SetImageFromBytes(content[:64])
+		Expect(t, errors.Is(err, ErrImageDecode)).ToBe(true)
+		Expect(t, err.Error()).Match("PNG")
+		Expect(t, client.pixImage).ToBe(uint64(0))
+		_, err = client.Text()
+		Expect(t, err).Not().ToBe(nil)
+	})
+}
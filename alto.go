@@ -0,0 +1,256 @@
+package gosseract
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// Namespace and schema of ALTO v4, see https://www.loc.gov/standards/alto/.
+const (
+	altoNamespace      = "http://www.loc.gov/standards/alto/ns-v4#"
+	altoSchemaLocation = altoNamespace + " http://www.loc.gov/alto/v4/alto-4-2.xsd"
+	xsiNamespace       = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// altoDocument is the root element of ALTO. The structs below only have the elements
+// and attributes written by ALTOText, in the order the schema requires.
+type altoDocument struct {
+	XMLName        xml.Name        `xml:"alto"`
+	Xmlns          string          `xml:"xmlns,attr"`
+	XmlnsXsi       string          `xml:"xmlns:xsi,attr"`
+	SchemaLocation string          `xml:"xsi:schemaLocation,attr"`
+	Description    altoDescription `xml:"Description"`
+	Page           altoPage        `xml:"Layout>Page"`
+}
+
+type altoDescription struct {
+	MeasurementUnit string         `xml:"MeasurementUnit"`
+	Processing      altoProcessing `xml:"Processing"`
+}
+
+type altoProcessing struct {
+	ID              string `xml:"ID,attr"`
+	SoftwareName    string `xml:"processingSoftware>softwareName"`
+	SoftwareVersion string `xml:"processingSoftware>softwareVersion,omitempty"`
+}
+
+type altoPage struct {
+	ID             string         `xml:"ID,attr"`
+	Width          int            `xml:"WIDTH,attr"`
+	Height         int            `xml:"HEIGHT,attr"`
+	PhysicalImgNr  int            `xml:"PHYSICAL_IMG_NR,attr"`
+	ProcessingRefs string         `xml:"PROCESSINGREFS,attr,omitempty"`
+	PrintSpace     altoPrintSpace `xml:"PrintSpace"`
+}
+
+// altoBox are the position attributes every element of the layout has.
+type altoBox struct {
+	HPos   int `xml:"HPOS,attr"`
+	VPos   int `xml:"VPOS,attr"`
+	Width  int `xml:"WIDTH,attr"`
+	Height int `xml:"HEIGHT,attr"`
+}
+
+type altoPrintSpace struct {
+	altoBox
+	Blocks []altoBlock `xml:",any"`
+}
+
+// altoBlock is a ComposedBlock of TextBlocks for a block of text, an Illustration for
+// an image and a GraphicalElement for a separator line, told apart by XMLName.
+type altoBlock struct {
+	XMLName xml.Name
+	ID      string `xml:"ID,attr"`
+	altoBox
+	TextBlocks []altoTextBlock `xml:"TextBlock"`
+}
+
+type altoTextBlock struct {
+	ID string `xml:"ID,attr"`
+	altoBox
+	Lang  string         `xml:"LANG,attr,omitempty"`
+	Lines []altoTextLine `xml:"TextLine"`
+}
+
+type altoTextLine struct {
+	ID string `xml:"ID,attr"`
+	altoBox
+	// Strings are the String and SP elements of the line, told apart by XMLName.
+	Strings []altoString `xml:",any"`
+}
+
+// altoString is a String for a word, or an SP for the space between two words,
+// which has no HEIGHT and no content.
+type altoString struct {
+	XMLName xml.Name
+	ID      string `xml:"ID,attr"`
+	HPos    int    `xml:"HPOS,attr"`
+	VPos    int    `xml:"VPOS,attr"`
+	Width   int    `xml:"WIDTH,attr"`
+	Height  int    `xml:"HEIGHT,attr,omitempty"`
+	Content string `xml:"CONTENT,attr,omitempty"`
+	// WC is the confidence of the word between 0 and 1.
+	WC    *float64 `xml:"WC,attr"`
+	Style string   `xml:"STYLE,attr,omitempty"`
+}
+
+// ALTOText recognizes the image and returns the result as ALTO v4 XML, the format
+// of the tesseract command line option "alto", which archives and libraries use.
+// The ALTO renderer of tesseract is not part of the wasm build, so it's converted
+// from the result of HOCRText. It follows the content model of alto-4-2.xsd, but
+// is not validated against the schema.
+func (client *Client) ALTOText() (string, error) {
+	return client.ALTOTextContext(context.Background())
+}
+
+// ALTOTextContext is ALTOText with a context.Context, see TextContext for the cancellation behaviour.
+func (client *Client) ALTOTextContext(ctx context.Context) (out string, err error) {
+	hocr, err := client.HOCRTextContext(ctx)
+	if err != nil {
+		return
+	}
+	doc, err := ParseHOCR(strings.NewReader(hocr))
+	if err != nil {
+		return
+	}
+	if len(doc.Pages) != 1 {
+		return "", fmt.Errorf("failed to convert hOCR to ALTO: expected 1 page, got %d", len(doc.Pages))
+	}
+	b, err := xml.MarshalIndent(newALTODocument(doc.Pages[0], client.Version()), "", "\t")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode ALTO: %w", err)
+	}
+	return xml.Header + string(b) + "\n", nil
+}
+
+// newALTODocument converts page to ALTO. The print space is the whole page, like the
+// tesseract renderer does.
+func newALTODocument(page *HOCRPage, version string) *altoDocument {
+	size, _ := page.Title.BBox()
+	pageNo, _ := page.Title.PPageNo()
+	doc := &altoDocument{
+		Xmlns:          altoNamespace,
+		XmlnsXsi:       xsiNamespace,
+		SchemaLocation: altoSchemaLocation,
+		Description: altoDescription{
+			MeasurementUnit: "pixel",
+			Processing: altoProcessing{
+				ID:              "OCR_0",
+				SoftwareName:    "tesseract",
+				SoftwareVersion: version,
+			},
+		},
+		Page: altoPage{
+			ID:             fmt.Sprintf("page_%d", pageNo),
+			Width:          size.Dx(),
+			Height:         size.Dy(),
+			PhysicalImgNr:  pageNo,
+			ProcessingRefs: "OCR_0",
+			PrintSpace:     altoPrintSpace{altoBox: newALTOBox(size)},
+		},
+	}
+
+	// IDs are numbered per element like the tesseract renderer does, they are unique within the document.
+	var blocks, textBlocks, lines, words, spaces int
+	for _, area := range page.Areas {
+		var name, prefix string
+		switch area.Class {
+		case "ocr_carea":
+			name, prefix = "ComposedBlock", "cblock"
+		case "ocr_photo":
+			name, prefix = "Illustration", "illustration"
+		case "ocr_separator":
+			name, prefix = "GraphicalElement", "graphic"
+		default:
+			continue
+		}
+		altoBlock := altoBlock{
+			XMLName: xml.Name{Local: name},
+			ID:      fmt.Sprintf("%s_%d", prefix, blocks),
+			altoBox: newALTOBox(bbox(area.Title)),
+		}
+		blocks++
+		for _, paragraph := range area.Paragraphs {
+			textBlock := altoTextBlock{
+				ID:      fmt.Sprintf("block_%d", textBlocks),
+				altoBox: newALTOBox(bbox(paragraph.Title)),
+				Lang:    paragraph.Lang,
+			}
+			textBlocks++
+			for _, line := range paragraph.Lines {
+				lineBox := bbox(line.Title)
+				textLine := altoTextLine{
+					ID:      fmt.Sprintf("line_%d", lines),
+					altoBox: newALTOBox(lineBox),
+				}
+				lines++
+				var previous *image.Rectangle
+				for _, word := range line.Words {
+					if word.Text == "" {
+						continue
+					}
+					box := bbox(word.Title)
+					if previous != nil {
+						width := box.Min.X - previous.Max.X
+						if width < 0 {
+							width = 0
+						}
+						textLine.Strings = append(textLine.Strings, altoString{
+							XMLName: xml.Name{Local: "SP"},
+							ID:      fmt.Sprintf("sp_%d", spaces),
+							HPos:    previous.Max.X,
+							VPos:    lineBox.Min.Y,
+							Width:   width,
+						})
+						spaces++
+					}
+					conf, _ := word.Title.XWConf()
+					wc := math.Round(conf) / 100
+					textLine.Strings = append(textLine.Strings, altoString{
+						XMLName: xml.Name{Local: "String"},
+						ID:      fmt.Sprintf("string_%d", words),
+						HPos:    box.Min.X,
+						VPos:    box.Min.Y,
+						Width:   box.Dx(),
+						Height:  box.Dy(),
+						Content: word.Text,
+						WC:      &wc,
+						Style:   altoStyle(word),
+					})
+					words++
+					previous = &box
+				}
+				textBlock.Lines = append(textBlock.Lines, textLine)
+			}
+			altoBlock.TextBlocks = append(altoBlock.TextBlocks, textBlock)
+		}
+		doc.Page.PrintSpace.Blocks = append(doc.Page.PrintSpace.Blocks, altoBlock)
+	}
+	return doc
+}
+
+// bbox returns the bounding box of title, which is empty if it's missing.
+func bbox(title HOCRTitle) image.Rectangle {
+	box, _ := title.BBox()
+	return box
+}
+
+func newALTOBox(rect image.Rectangle) altoBox {
+	return altoBox{HPos: rect.Min.X, VPos: rect.Min.Y, Width: rect.Dx(), Height: rect.Dy()}
+}
+
+// altoStyle returns the fontStylesType of ALTO for word.
+func altoStyle(word *HOCRWord) string {
+	var styles []string
+	if word.Bold {
+		styles = append(styles, "bold")
+	}
+	if word.Italic {
+		styles = append(styles, "italics")
+	}
+	return strings.Join(styles, " ")
+}
@@ -1,6 +1,7 @@
 package gosseract
 
 import (
+	"context"
 	"io"
 	"io/ioutil"
 	"os"
@@ -8,18 +9,18 @@ import (
 )
 
 func BenchmarkClient_New(b *testing.B) {
-	client := NewClient()
+	client, _ := NewClient()
 	client.Close()
 
 	for i := 0; i < b.N; i++ {
-		client := NewClient()
+		client, _ := NewClient()
 		client.Close()
 	}
 }
 
 func BenchmarkClient_Text(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		client := NewClient()
+		client, _ := NewClient()
 		client.SetImage("./test/data/001-helloworld.png")
 		client.Text()
 		client.Close()
@@ -27,7 +28,7 @@ func BenchmarkClient_Text(b *testing.B) {
 }
 
 func BenchmarkClient_Text2(b *testing.B) {
-	client := NewClient()
+	client, _ := NewClient()
 	for i := 0; i < b.N; i++ {
 		client.SetImage("./test/data/001-helloworld.png")
 		client.Text()
@@ -36,7 +37,7 @@ func BenchmarkClient_Text2(b *testing.B) {
 }
 
 func BenchmarkClient_Text3(b *testing.B) {
-	client := NewClient()
+	client, _ := NewClient()
 	for i := 0; i < b.N; i++ {
 		file, _ := os.Open("./test/data/001-helloworld.png")
 		image, _ := ioutil.ReadAll(io.LimitReader(file, 1024*1024*50))
@@ -46,8 +47,19 @@ func BenchmarkClient_Text3(b *testing.B) {
 	client.Close()
 }
 
+func BenchmarkClient_Text3Reader(b *testing.B) {
+	client, _ := NewClient()
+	for i := 0; i < b.N; i++ {
+		file, _ := os.Open("./test/data/001-helloworld.png")
+		client.SetImageFromReader(file, 1024*1024*50)
+		file.Close()
+		client.Text()
+	}
+	client.Close()
+}
+
 func BenchmarkClient_Text4(b *testing.B) {
-	client := NewClient()
+	client, _ := NewClient()
 	file, _ := os.Open("./test/data/001-helloworld.png")
 	image, _ := ioutil.ReadAll(io.LimitReader(file, 1024*1024*50))
 	client.SetImageFromBytes(image)
@@ -59,7 +71,7 @@ func BenchmarkClient_Text4(b *testing.B) {
 
 func BenchmarkClient_GetBoundingBoxes(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		client := NewClient()
+		client, _ := NewClient()
 		client.SetImage("./test/data/003-longer-text.png")
 		client.GetBoundingBoxes(3)
 		client.Close()
@@ -68,9 +80,20 @@ func BenchmarkClient_GetBoundingBoxes(b *testing.B) {
 
 func BenchmarkClient_GetBoundingBoxesVerbose(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		client := NewClient()
+		client, _ := NewClient()
 		client.SetImage("./test/data/003-longer-text.png")
 		client.GetBoundingBoxesVerbose()
 		client.Close()
 	}
 }
+
+func BenchmarkPool_Text(b *testing.B) {
+	pool, _ := NewPool(1)
+	for i := 0; i < b.N; i++ {
+		client, _ := pool.Acquire(context.Background())
+		client.SetImage("./test/data/001-helloworld.png")
+		client.Text()
+		pool.Release(client)
+	}
+	pool.Close()
+}
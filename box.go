@@ -0,0 +1,190 @@
+package gosseract
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// BoxLine is a line of a box file. The coordinates of box files have their origin at the
+// bottom left corner of the image, so Box.Min is the left bottom corner and Box.Max the
+// right top corner of the box, with y counting up from the bottom of the image.
+type BoxLine struct {
+	// Text is a symbol in box files. In LSTM box files it's a space between words, and
+	// a tab at the end of a line. In WordStr box files it's the text of a line, or a tab,
+	// which marks the end of the line with a box right of it.
+	Text string
+	Box  image.Rectangle
+	// Page is the 0-based number of the page.
+	Page int
+}
+
+// BoxText recognizes the image and returns a box file, with a line for every symbol,
+// the training data format of the legacy engine written by `tesseract image out makebox`.
+// See BoxLine for the coordinates.
+func (client *Client) BoxText() (string, error) {
+	return client.BoxTextContext(context.Background())
+}
+
+// BoxTextContext is BoxText with a context.Context, see TextContext for the cancellation behaviour.
+func (client *Client) BoxTextContext(ctx context.Context) (string, error) {
+	return client.boxText(ctx, func(t *tesseractApi) func(params ...uint64) ([]uint64, error) {
+		return t.BoxText
+	})
+}
+
+// LSTMBoxText recognizes the image and returns an LSTM box file, the training data format
+// of the LSTM engine written by `tesseract image out lstmbox`: a line for every symbol,
+// a space between words and a tab at the end of every line. See BoxLine for the coordinates.
+func (client *Client) LSTMBoxText() (string, error) {
+	return client.LSTMBoxTextContext(context.Background())
+}
+
+// LSTMBoxTextContext is LSTMBoxText with a context.Context, see TextContext for the cancellation behaviour.
+func (client *Client) LSTMBoxTextContext(ctx context.Context) (string, error) {
+	return client.boxText(ctx, func(t *tesseractApi) func(params ...uint64) ([]uint64, error) {
+		return t.LSTMBoxText
+	})
+}
+
+// WordStrBoxText recognizes the image and returns a WordStr box file, written by
+// `tesseract image out wordstrbox`: a line with the text and the box of every line
+// of text, which is easier to correct than the box of every symbol. See BoxLine for the coordinates.
+func (client *Client) WordStrBoxText() (string, error) {
+	return client.WordStrBoxTextContext(context.Background())
+}
+
+// WordStrBoxTextContext is WordStrBoxText with a context.Context, see TextContext for the cancellation behaviour.
+func (client *Client) WordStrBoxTextContext(ctx context.Context) (string, error) {
+	return client.boxText(ctx, func(t *tesseractApi) func(params ...uint64) ([]uint64, error) {
+		return t.WordStrBoxText
+	})
+}
+
+// boxText recognizes the image and returns the text of the export fn selects.
+// The export is selected after useContext, which checks that the client is not closed.
+func (client *Client) boxText(ctx context.Context, fn func(*tesseractApi) func(params ...uint64) ([]uint64, error)) (out string, err error) {
+	if err = client.useContext(ctx); err != nil {
+		return
+	}
+	defer client.wasm.useContext(ctx)()
+	if err = client.init(); err != nil {
+		return
+	}
+	res, err := fn(client.wasm)(client.api)
+	if err != nil {
+		return
+	}
+	if res[0] == 0 {
+		return "", fmt.Errorf("failed to recognize the image")
+	}
+	defer client.wasm.free(res[0])
+	return client.wasm.ReadString(res[0])
+}
+
+// ParseBoxText reads a box file as returned by BoxText or LSTMBoxText.
+func ParseBoxText(r io.Reader) ([]BoxLine, error) {
+	return parseBoxFile(r, false)
+}
+
+// ParseWordStrBoxText reads a WordStr box file as returned by WordStrBoxText.
+func ParseWordStrBoxText(r io.Reader) ([]BoxLine, error) {
+	return parseBoxFile(r, true)
+}
+
+// FormatBoxText writes lines in the format of BoxText and LSTMBoxText.
+func FormatBoxText(lines []BoxLine) string {
+	var b strings.Builder
+	for _, line := range lines {
+		fmt.Fprintf(&b, "%s %s\n", line.Text, line.coordinates())
+	}
+	return b.String()
+}
+
+// FormatWordStrBoxText writes lines in the format of WordStrBoxText.
+func FormatWordStrBoxText(lines []BoxLine) string {
+	var b strings.Builder
+	for _, line := range lines {
+		if line.Text == "\t" {
+			fmt.Fprintf(&b, "\t %s\n", line.coordinates())
+			continue
+		}
+		fmt.Fprintf(&b, "WordStr %s #%s\n", line.coordinates(), line.Text)
+	}
+	return b.String()
+}
+
+func (line BoxLine) coordinates() string {
+	return fmt.Sprintf("%d %d %d %d %d", line.Box.Min.X, line.Box.Min.Y, line.Box.Max.X, line.Box.Max.Y, line.Page)
+}
+
+// parseBoxFile reads the lines of a box file, which are WordStr lines, if wordStr is set.
+func parseBoxFile(r io.Reader, wordStr bool) ([]BoxLine, error) {
+	var lines []BoxLine
+	reader := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		text, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if text = strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r"); text != "" {
+			var line BoxLine
+			var parseErr error
+			if wordStr && strings.HasPrefix(text, "WordStr ") {
+				line, parseErr = parseWordStrLine(text)
+			} else {
+				line, parseErr = parseBoxLine(text)
+			}
+			if parseErr != nil {
+				return nil, fmt.Errorf("failed to parse line %d of box file: %w", n, parseErr)
+			}
+			lines = append(lines, line)
+		}
+		if err == io.EOF {
+			return lines, nil
+		}
+	}
+}
+
+// parseBoxLine parses `<text> <left> <bottom> <right> <top> <page>`, where text may be a space.
+func parseBoxLine(text string) (line BoxLine, err error) {
+	var numbers [5]int
+	for i := len(numbers) - 1; i >= 0; i-- {
+		sep := strings.LastIndexByte(text, ' ')
+		if sep < 0 {
+			return line, fmt.Errorf("expected a symbol and 5 numbers")
+		}
+		if numbers[i], err = strconv.Atoi(text[sep+1:]); err != nil {
+			return
+		}
+		text = text[:sep]
+	}
+	return newBoxLine(text, numbers), nil
+}
+
+// parseWordStrLine parses `WordStr <left> <bottom> <right> <top> <page> #<text>`.
+func parseWordStrLine(text string) (line BoxLine, err error) {
+	fields := strings.SplitN(strings.TrimPrefix(text, "WordStr "), " ", 6)
+	if len(fields) != 6 || !strings.HasPrefix(fields[5], "#") {
+		return line, fmt.Errorf("expected 5 numbers and the text after #")
+	}
+	var numbers [5]int
+	for i := range numbers {
+		if numbers[i], err = strconv.Atoi(fields[i]); err != nil {
+			return
+		}
+	}
+	return newBoxLine(fields[5][1:], numbers), nil
+}
+
+func newBoxLine(text string, numbers [5]int) BoxLine {
+	return BoxLine{
+		Text: text,
+		Box:  image.Rectangle{Min: image.Pt(numbers[0], numbers[1]), Max: image.Pt(numbers[2], numbers[3])},
+		Page: numbers[4],
+	}
+}
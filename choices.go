@@ -0,0 +1,141 @@
+package gosseract
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+)
+
+// choiceSize is sizeof(struct choice) in wasm32, see tessbridge.h.
+const choiceSize = 28
+
+// Kinds of the records returned by GetSymbolChoices, see tessbridge.cpp.
+const (
+	choiceSymbol = iota
+	choiceAlternative
+	choiceTimestep
+	choiceTimestepAlternative
+)
+
+// SymbolChoice is a candidate for a symbol.
+type SymbolChoice struct {
+	Text string
+	// Confidence is between 0 and 100. For the candidates of Timesteps, it's the probability
+	// the LSTM engine gave the character at the timestep, in percent: the ones of a timestep
+	// add up to at most 100.
+	Confidence float64
+}
+
+// SymbolResult is a recognized symbol with the candidates tesseract considered.
+type SymbolResult struct {
+	Result
+	// Choices are the candidates for the symbol, best first. The LSTM engine only keeps
+	// the best one, unless the variable lstm_choice_mode is set to 1 or 2.
+	Choices []SymbolChoice
+	// Timesteps are the candidates of every timestep of the LSTM engine, which the symbol
+	// spans. They are only given if the variable lstm_choice_mode is set to 1 or 2.
+	Timesteps [][]SymbolChoice
+}
+
+// GetSymbolChoices recognizes the image and returns every symbol with its candidates,
+// e.g. to check the runner-up characters of a serial number.
+func (client *Client) GetSymbolChoices() ([]SymbolResult, error) {
+	return client.GetSymbolChoicesContext(context.Background())
+}
+
+// GetSymbolChoicesContext is GetSymbolChoices with a context.Context, see TextContext for the cancellation behaviour.
+func (client *Client) GetSymbolChoicesContext(ctx context.Context) (out []SymbolResult, err error) {
+	if err = client.useContext(ctx); err != nil {
+		return
+	}
+	defer client.wasm.useContext(ctx)()
+	if err = client.init(); err != nil {
+		return
+	}
+	res, err := client.wasm.GetSymbolChoices(client.api)
+	if err != nil {
+		return
+	}
+	records, err := client.readChoices(res[0])
+	if err != nil {
+		return
+	}
+	return newSymbolResults(records), nil
+}
+
+// choiceRecord is a struct choice read from the module memory.
+type choiceRecord struct {
+	kind int
+	Result
+}
+
+// readChoices reads the records returned by GetSymbolChoices, and frees them.
+func (client *Client) readChoices(choicesPtr uint64) ([]choiceRecord, error) {
+	mem := client.wasm.module.Memory()
+	defer client.wasm.free(choicesPtr)
+	length, ok := mem.ReadUint32Le(uint32(choicesPtr))
+	if !ok {
+		return nil, &WasmError{Func: "GetSymbolChoices", Kind: ErrWasmTrap, Err: fmt.Errorf("pointer %d is out of range", choicesPtr)}
+	}
+	itemsPtr, _ := mem.ReadUint32Le(uint32(choicesPtr) + 4)
+	defer client.wasm.free(uint64(itemsPtr))
+
+	readInt := func(offset uint32) int {
+		x, _ := mem.ReadUint32Le(itemsPtr + offset)
+		return int(int32(x))
+	}
+
+	records := make([]choiceRecord, 0, length)
+	for i := uint32(0); i < length; i++ {
+		base := choiceSize * i
+		textPtr := uint64(uint32(readInt(base + 20)))
+		text, err := client.wasm.ReadString(textPtr)
+		if err != nil {
+			return nil, err
+		}
+		if textPtr != 0 {
+			client.wasm.free(textPtr)
+		}
+		records = append(records, choiceRecord{
+			kind: readInt(base),
+			Result: Result{
+				Box:        image.Rect(readInt(base+4), readInt(base+8), readInt(base+12), readInt(base+16)),
+				Text:       text,
+				Confidence: float64(math.Float32frombits(uint32(readInt(base + 24)))),
+			},
+		})
+	}
+	return records, nil
+}
+
+// newSymbolResults groups records, where every symbol is followed by its candidates and timesteps.
+func newSymbolResults(records []choiceRecord) []SymbolResult {
+	var out []SymbolResult
+	for _, record := range records {
+		if record.kind == choiceSymbol {
+			out = append(out, SymbolResult{Result: record.Result})
+			continue
+		}
+		if len(out) == 0 {
+			continue
+		}
+		symbol := &out[len(out)-1]
+		choice := SymbolChoice{Text: record.Text, Confidence: record.Confidence}
+		switch record.kind {
+		case choiceAlternative:
+			symbol.Choices = append(symbol.Choices, choice)
+		case choiceTimestep:
+			symbol.Timesteps = append(symbol.Timesteps, []SymbolChoice{})
+		case choiceTimestepAlternative:
+			// Tesseract keeps the timesteps as probabilities between 0 and 1.
+			choice.Confidence *= 100
+			if len(symbol.Timesteps) == 0 {
+				symbol.Timesteps = append(symbol.Timesteps, []SymbolChoice{})
+			}
+			last := len(symbol.Timesteps) - 1
+			symbol.Timesteps[last] = append(symbol.Timesteps[last], choice)
+		}
+	}
+	return out
+}
@@ -1,8 +1,11 @@
 package gosseract
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"image"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -10,19 +13,38 @@ import (
 )
 
 // Version returns the version of Tesseract-OCR
+// An empty string is returned if the wasm module could not be loaded.
 func Version() string {
-	wasm := newApi()
+	wasm, err := newApi()
+	if err != nil {
+		return ""
+	}
 	defer wasm.Close()
-	api := wasm.Create()[0]
-	defer wasm.Free(api)
-	versionPtr := wasm.Version(api)[0]
-	defer wasm.free(versionPtr)
-	return wasm.ReadString(versionPtr)
+	res, err := wasm.Create()
+	if err != nil {
+		return ""
+	}
+	defer wasm.Free(res[0])
+	return readVersion(wasm, res[0])
+}
+
+func readVersion(wasm *tesseractApi, api uint64) string {
+	res, err := wasm.Version(api)
+	if err != nil {
+		return ""
+	}
+	// Version returns a static string, it must not be freed.
+	version, _ := wasm.ReadString(res[0])
+	return version
 }
 
 // ClearPersistentCache clears any library-level memory caches. There are a variety of expensive-to-load constant data structures (mostly language dictionaries) that are cached globally – surviving the Init() and End() of individual TessBaseAPI's. This function allows the clearing of these caches.
-func (client *Client) ClearPersistentCache() {
-	client.wasm.ClearPersistentCache(client.api)
+func (client *Client) ClearPersistentCache() error {
+	if err := client.checkAPI(); err != nil {
+		return err
+	}
+	_, err := client.wasm.ClearPersistentCache(client.api)
+	return err
 }
 
 // Client is argument builder for tesseract::TessBaseAPI.
@@ -57,121 +79,267 @@ type Client struct {
 	// TODO: Fix link to official page
 	ConfigFilePath string
 
+	// ocrEngineMode is passed to TessBaseAPI::Init, OEM_DEFAULT as default.
+	ocrEngineMode OcrEngineMode
+
+	// rectangle is the region to recognize, the whole image if it's empty.
+	rectangle image.Rectangle
+
+	// sourceResolution is the DPI of the image given by SetSourceResolution, 0 to use the one of the image.
+	sourceResolution int
+
+	// resolutionClamp specifies whether the DPI of an image below 70 is raised to 70, see SetResolutionClamp.
+	resolutionClamp bool
+
+	// imageSet is set while pixImage and the settings of the recognition are passed to TessBaseAPI
+	// unchanged, so it keeps the results of the last recognition, see MeanTextConf.
+	imageSet bool
+
 	// internal flag to check if the instance should be initialized again
 	// i.e, we should create a new gosseract client when language or config file change
 	shouldInit bool
+
+	// closed is set by Close, every later call returns ErrClientClosed.
+	closed bool
 }
 
 // NewClient construct new Client. It's due to caller to Close this client.
-func NewClient() *Client {
-	wasm := newApi()
-	client := &Client{
-		wasm:       wasm,
-		api:        wasm.Create()[0],
-		Variables:  map[SettableVariable]string{},
-		Trim:       true,
-		shouldInit: true,
-		Languages:  []string{"eng"},
+func NewClient() (*Client, error) {
+	wasm, err := newApi()
+	if err != nil {
+		return nil, err
 	}
-	return client
+	return newClient(wasm)
 }
 
 // NewClient construct new Client with a FS that will be mounted at '/custom/'.
 // The file system can be used to provide (embedded) traineddata or other files to tesseract.
 // It's due to caller to Close this client.
-func NewClientWithFS(fs fs.FS) *Client {
-	wasm := newApiWithFS(fs)
+func NewClientWithFS(fs fs.FS) (*Client, error) {
+	return NewClientWithOptions(ClientOptions{FS: fs})
+}
+
+// NewClientWithOptions construct new Client, which only sees the filesystem exposed by opts.
+// It's due to caller to Close this client.
+func NewClientWithOptions(opts ClientOptions) (*Client, error) {
+	wasm, err := newApiWithOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(wasm)
+}
+
+func newClient(wasm *tesseractApi) (*Client, error) {
+	res, err := wasm.Create()
+	if err != nil {
+		wasm.Close()
+		return nil, err
+	}
 	client := &Client{
-		wasm:       wasm,
-		api:        wasm.Create()[0],
-		Variables:  map[SettableVariable]string{},
-		Trim:       true,
-		shouldInit: true,
-		Languages:  []string{"eng"},
+		wasm:            wasm,
+		api:             res[0],
+		Variables:       map[SettableVariable]string{},
+		Trim:            true,
+		shouldInit:      true,
+		Languages:       []string{"eng"},
+		ocrEngineMode:   OEM_DEFAULT,
+		resolutionClamp: true,
 	}
-	return client
+	return client, nil
 }
 
 // Close frees allocated API. This MUST be called for ANY client constructed by "NewClient" function.
+// Errors of the wasm module are ignored here, because the module is thrown away anyway.
 func (client *Client) Close() (err error) {
-	// defer func() {
-	// 	if e := recover(); e != nil {
-	// 		err = fmt.Errorf("%v", e)
-	// 	}
-	// }()
+	if client.wasm == nil {
+		return nil
+	}
 	client.wasm.Clear(client.api)
 	client.wasm.Free(client.api)
 	if client.pixImage != 0 {
 		client.wasm.DestroyPixImage(client.pixImage)
 		client.pixImage = 0
 	}
-	client.wasm.Close()
-	client.wasm.module.Close(client.wasm.context)
+	err = client.wasm.Close()
+	client.wasm = nil
+	client.api = 0
+	client.closed = true
 	return err
 }
 
 // Version provides the version of Tesseract used by this client.
 func (client *Client) Version() string {
-	versionPtr := client.wasm.Version(client.api)[0]
-	defer client.wasm.free(versionPtr)
-	return client.wasm.ReadString(versionPtr)
+	if client.checkAPI() != nil {
+		return ""
+	}
+	return readVersion(client.wasm, client.api)
 }
 
 // SetImage sets path to image file to be processed OCR.
+// See SetImageFromBytes for the errors reported for files which cannot be decoded.
 func (client *Client) SetImage(imagepath string) error {
 
-	if client.api == 0 {
-		return fmt.Errorf("TessBaseAPI is not constructed, please use `gosseract.NewClient`")
+	if err := client.checkAPI(); err != nil {
+		return err
 	}
 	if imagepath == "" {
 		return fmt.Errorf("image path cannot be empty")
 	}
-	if _, err := os.Stat(imagepath); err != nil {
-		return fmt.Errorf("cannot detect the stat of specified file: %v", err)
+	if _, err := client.wasm.sandbox.stat(imagepath); err != nil {
+		return fmt.Errorf("cannot detect the stat of specified file: %w", err)
 	}
 
-	imagepath, _ = filepath.Abs(imagepath)
+	head, err := client.wasm.sandbox.readHead(imagepath)
+	if err != nil {
+		return fmt.Errorf("cannot read specified file: %w", err)
+	}
+	if err := checkImageFormat(head); err != nil {
+		return err
+	}
 
-	if client.pixImage != 0 {
-		client.wasm.DestroyPixImage(client.pixImage)
-		client.pixImage = 0
+	imagepath, err = client.wasm.sandbox.guestPath(imagepath)
+	if err != nil {
+		return err
+	}
+
+	if err := client.destroyPixImage(); err != nil {
+		return err
 	}
 
-	imagepathPtr := client.wasm.malloc(uint64(len(imagepath) + 1))[0]
+	imagepathPtr, err := client.wasm.WriteString(imagepath)
+	if err != nil {
+		return err
+	}
 	defer client.wasm.free(imagepathPtr)
-	client.wasm.module.Memory().Write(uint32(imagepathPtr), append([]byte(imagepath), 0))
 
-	img := client.wasm.CreatePixImageByFilepath(imagepathPtr)[0]
-	client.pixImage = img
+	res, err := client.wasm.CreatePixImageByFilepath(imagepathPtr)
+	if err != nil || res[0] == 0 {
+		return decodeImageError(head, err)
+	}
+	client.pixImage = res[0]
 
 	return nil
 }
 
 // SetImageFromBytes sets the image data to be processed OCR.
+// Formats leptonica cannot decode are rejected with ErrUnsupportedImageFormat, and corrupted
+// data with ErrImageDecode. For some corruptions leptonica exits, which closes the client.
 func (client *Client) SetImageFromBytes(data []byte) error {
 
-	if client.api == 0 {
-		return fmt.Errorf("TessBaseAPI is not constructed, please use `gosseract.NewClient`")
+	if err := client.checkAPI(); err != nil {
+		return err
 	}
 	if len(data) == 0 {
 		return fmt.Errorf("image data cannot be empty")
 	}
+	if err := checkImageFormat(data); err != nil {
+		return err
+	}
 
-	if client.pixImage != 0 {
-		client.wasm.DestroyPixImage(client.pixImage)
-		client.pixImage = 0
+	if err := client.destroyPixImage(); err != nil {
+		return err
 	}
 
-	imagePtr := client.wasm.malloc(uint64(len(data)))[0]
+	imagePtr, err := client.wasm.WriteBytes(data)
+	if err != nil {
+		return err
+	}
 	defer client.wasm.free(imagePtr)
-	client.wasm.module.Memory().Write(uint32(imagePtr), data)
 
-	img := client.wasm.CreatePixImageFromBytes(imagePtr, uint64(len(data)))[0]
-	client.pixImage = img
+	res, err := client.wasm.CreatePixImageFromBytes(imagePtr, uint64(len(data)))
+	if err != nil || res[0] == 0 {
+		return decodeImageError(data, err)
+	}
+	client.pixImage = res[0]
 
 	return nil
 }
 
+// SetImageFromReader sets the image data read from r to be processed OCR.
+// The data is streamed into the module memory, and ErrImageTooLarge is returned
+// as soon as more than maxBytes are read. If it fails, the image set before is kept.
+func (client *Client) SetImageFromReader(r io.Reader, maxBytes int64) error {
+
+	if err := client.checkAPI(); err != nil {
+		return err
+	}
+	if maxBytes <= 0 {
+		return fmt.Errorf("max bytes must be positive")
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	head = head[:n]
+	if len(head) == 0 {
+		return fmt.Errorf("image data cannot be empty")
+	}
+	if int64(len(head)) > maxBytes {
+		return fmt.Errorf("%w: more than %d bytes", ErrImageTooLarge, maxBytes)
+	}
+	if err := checkImageFormat(head); err != nil {
+		return err
+	}
+
+	imagePtr, size, err := client.wasm.readAll(io.MultiReader(bytes.NewReader(head), r), maxBytes)
+	if err != nil {
+		return err
+	}
+	defer client.wasm.free(imagePtr)
+
+	res, err := client.wasm.CreatePixImageFromBytes(imagePtr, size)
+	if err != nil || res[0] == 0 {
+		return decodeImageError(head, err)
+	}
+	// The image set before is only replaced once the new one is decoded.
+	if err := client.destroyPixImage(); err != nil {
+		client.wasm.DestroyPixImage(res[0])
+		return err
+	}
+	client.pixImage = res[0]
+
+	return nil
+}
+
+// SetImageFromImage sets the decoded image to be processed OCR. The pixels are copied
+// into the module memory directly, so there is no need to encode img first.
+// *image.Gray and gray *image.Paletted images are kept 8 bit gray, all others become RGB;
+// transparent pixels are composited onto white.
+func (client *Client) SetImageFromImage(img image.Image) error {
+
+	if err := client.checkAPI(); err != nil {
+		return err
+	}
+	if img == nil || img.Bounds().Empty() {
+		return fmt.Errorf("image cannot be empty")
+	}
+
+	if err := client.destroyPixImage(); err != nil {
+		return err
+	}
+
+	pix, err := client.wasm.createPixImage(img)
+	if err != nil {
+		return err
+	}
+	client.pixImage = pix
+
+	return nil
+}
+
+// destroyPixImage releases the image currently set, if any.
+func (client *Client) destroyPixImage() error {
+	client.imageSet = false
+	if client.pixImage == 0 {
+		return nil
+	}
+	_, err := client.wasm.DestroyPixImage(client.pixImage)
+	client.pixImage = 0
+	return err
+}
+
 // SetLanguage sets languages to use. English as default.
 func (client *Client) SetLanguage(langs ...string) error {
 	if len(langs) == 0 {
@@ -187,31 +355,19 @@ func (client *Client) SetLanguage(langs ...string) error {
 
 // DisableOutput ...
 func (client *Client) DisableOutput() error {
-	err := client.SetVariable(DEBUG_FILE, os.DevNull)
-
-	client.setVariablesToInitializedAPIIfNeeded()
-
-	return err
+	return client.SetVariable(DEBUG_FILE, os.DevNull)
 }
 
 // SetWhitelist sets whitelist chars.
 // See official documentation for whitelist here https://tesseract-ocr.github.io/tessdoc/ImproveQuality#dictionaries-word-lists-and-patterns
 func (client *Client) SetWhitelist(whitelist string) error {
-	err := client.SetVariable(TESSEDIT_CHAR_WHITELIST, whitelist)
-
-	client.setVariablesToInitializedAPIIfNeeded()
-
-	return err
+	return client.SetVariable(TESSEDIT_CHAR_WHITELIST, whitelist)
 }
 
 // SetBlacklist sets blacklist chars.
 // See official documentation for blacklist here https://tesseract-ocr.github.io/tessdoc/ImproveQuality#dictionaries-word-lists-and-patterns
 func (client *Client) SetBlacklist(blacklist string) error {
-	err := client.SetVariable(TESSEDIT_CHAR_BLACKLIST, blacklist)
-
-	client.setVariablesToInitializedAPIIfNeeded()
-
-	return err
+	return client.SetVariable(TESSEDIT_CHAR_BLACKLIST, blacklist)
 }
 
 // SetVariable sets parameters, representing tesseract::TessBaseAPI->SetVariable.
@@ -219,32 +375,150 @@ func (client *Client) SetBlacklist(blacklist string) error {
 // Because `api->SetVariable` must be called after `api->Init`, this method cannot detect unexpected key for variables.
 // Check `client.setVariablesToInitializedAPI` for more information.
 func (client *Client) SetVariable(key SettableVariable, value string) error {
+	if err := client.checkAPI(); err != nil {
+		return err
+	}
 	client.Variables[key] = value
+	client.imageSet = false
 
-	client.setVariablesToInitializedAPIIfNeeded()
-
-	return nil
+	return client.setVariablesToInitializedAPIIfNeeded()
 }
 
 // SetPageSegMode sets "Page Segmentation Mode" (PSM) to detect layout of characters.
 // See official documentation for PSM here https://tesseract-ocr.github.io/tessdoc/ImproveQuality#page-segmentation-method
 // See https://github.com/otiai10/gosseract/issues/52 for more information.
 func (client *Client) SetPageSegMode(mode PageSegMode) error {
-	client.wasm.SetPageSegMode(client.api, uint64(mode))
+	if err := client.checkAPI(); err != nil {
+		return err
+	}
+	client.imageSet = false
+	_, err := client.wasm.SetPageSegMode(client.api, uint64(mode))
+	return err
+}
+
+// SetOcrEngineMode sets the OCR engine mode, OEM_DEFAULT as default.
+// Changing it initializes TessBaseAPI again on the next recognition.
+// ErrLegacyEngineDisabled is returned for the modes using the legacy engine,
+// if tesseract was built with DISABLED_LEGACY_ENGINE=ON, as the embedded wasm is.
+// ErrMissingExport is returned for these modes if the wasm predates the mode being
+// passed to TessBaseAPI::Init, as it would run the LSTM engine instead.
+func (client *Client) SetOcrEngineMode(mode OcrEngineMode) error {
+	if err := client.checkAPI(); err != nil {
+		return err
+	}
+	if mode < 0 || mode >= OEM_COUNT {
+		return fmt.Errorf("invalid OCR engine mode %d", mode)
+	}
+	if mode == client.ocrEngineMode {
+		return nil
+	}
+	if mode == OEM_TESSERACT_ONLY || mode == OEM_TESSERACT_LSTM_COMBINED {
+		hasLegacy, err := client.wasm.hasLegacyEngine()
+		if err != nil {
+			return err
+		}
+		if !hasLegacy {
+			return fmt.Errorf("%w: OCR engine mode %d", ErrLegacyEngineDisabled, mode)
+		}
+	}
+	client.ocrEngineMode = mode
+	client.flagForInit()
+	return nil
+}
+
+// SetRectangle restricts recognition to rect of the image, until ClearRectangle is called.
+// It's kept when the image is changed, so the same region can be recognized on many images.
+// The results, e.g. of GetBoundingBoxes, are still in coordinates of the whole image.
+func (client *Client) SetRectangle(rect image.Rectangle) error {
+	if err := checkRectangle(rect); err != nil {
+		return err
+	}
+	client.rectangle = rect
+	client.imageSet = false
+	return nil
+}
+
+// ClearRectangle recognizes the whole image again, see SetRectangle.
+func (client *Client) ClearRectangle() {
+	client.rectangle = image.Rectangle{}
+	client.imageSet = false
+}
+
+func checkRectangle(rect image.Rectangle) error {
+	if rect.Empty() {
+		return fmt.Errorf("rectangle %v is empty", rect)
+	}
+	if rect.Min.X < 0 || rect.Min.Y < 0 {
+		return fmt.Errorf("rectangle %v is outside of the image", rect)
+	}
+	return nil
+}
+
+// SetSourceResolution sets the DPI of the image, which is recorded in hOCR output.
+// It takes precedence over the DPI in the metadata of the image, until it's set to 0 again.
+// ErrMissingExport is returned if the wasm was built without SetSourceResolution.
+func (client *Client) SetSourceResolution(dpi int) error {
+	if err := client.checkAPI(); err != nil {
+		return err
+	}
+	if dpi < 0 {
+		return fmt.Errorf("invalid source resolution %d", dpi)
+	}
+	if dpi != 0 {
+		if err := client.wasm.requireExport("SetSourceResolution"); err != nil {
+			return err
+		}
+	}
+	client.sourceResolution = dpi
+	client.imageSet = false
+	return nil
+}
+
+// GetSourceResolution returns the DPI of the image, which tesseract uses for recognition.
+// The image must be set before.
+func (client *Client) GetSourceResolution() (int, error) {
+	if err := client.init(); err != nil {
+		return 0, err
+	}
+	res, err := client.wasm.GetSourceResolution(client.api)
+	if err != nil {
+		return 0, err
+	}
+	return int(int32(res[0])), nil
+}
+
+// SetResolutionClamp specifies whether the DPI of an image is raised to 70, if its metadata
+// gives a lower one. It's enabled as default. Otherwise tesseract estimates the DPI of images
+// without a credible one from the size of the text. SetSourceResolution takes precedence.
+// ErrMissingExport is returned for disabling it, if the SetPixImage of the wasm predates the clamp argument.
+func (client *Client) SetResolutionClamp(clamp bool) error {
+	if err := client.checkAPI(); err != nil {
+		return err
+	}
+	if !clamp && !client.wasm.pixImageTakesClamp() {
+		return &WasmError{Func: "SetPixImage", Kind: ErrMissingExport, Err: fmt.Errorf("the clamp argument is missing")}
+	}
+	client.resolutionClamp = clamp
+	client.imageSet = false
 	return nil
 }
 
 // SetConfigFile sets the file path to config file.
+// The path must be visible to tesseract, see ClientOptions.
 func (client *Client) SetConfigFile(fpath string) error {
-	info, err := os.Stat(fpath)
+	if err := client.checkAPI(); err != nil {
+		return err
+	}
+	info, err := client.wasm.sandbox.stat(fpath)
 	if err != nil {
 		return err
 	}
 	if info.IsDir() {
 		return fmt.Errorf("the specified config file path seems to be a directory")
 	}
-	client.ConfigFilePath, err = filepath.Abs(fpath)
-	if err != nil {
+	if client.wasm.sandbox.mode == HostFSNone {
+		client.ConfigFilePath = fpath
+	} else if client.ConfigFilePath, err = filepath.Abs(fpath); err != nil {
 		return err
 	}
 
@@ -255,55 +529,124 @@ func (client *Client) SetConfigFile(fpath string) error {
 
 // SetTessdataPrefix sets path to the models directory.
 // Environment variable TESSDATA_PREFIX is used as default.
+// The path must be visible to tesseract, see ClientOptions.
 func (client *Client) SetTessdataPrefix(prefix string) error {
+	if err := client.checkAPI(); err != nil {
+		return err
+	}
 	if prefix == "" {
 		return fmt.Errorf("tessdata prefix could not be empty")
 	}
-	client.TessdataPrefix, _ = filepath.Abs(prefix)
+	if _, err := client.wasm.sandbox.guestPath(prefix); err != nil {
+		return err
+	}
+	if client.wasm.sandbox.mode == HostFSNone {
+		client.TessdataPrefix = prefix
+	} else {
+		client.TessdataPrefix, _ = filepath.Abs(prefix)
+	}
 	client.flagForInit()
 	return nil
 }
 
-func (client *Client) setTessdataPrefixWithFS(prefix string, fs fs.FS) {
-}
-
 // Initialize tesseract::TessBaseAPI
 func (client *Client) init() error {
 
-	if !client.shouldInit {
-		client.wasm.SetPixImage(client.api, client.pixImage)
+	if err := client.checkAPI(); err != nil {
+		return err
+	}
+
+	if client.shouldInit {
+		if err := client.initAPI(); err != nil {
+			return err
+		}
+	}
+
+	if client.pixImage == 0 {
+		return fmt.Errorf("PixImage is not set, use SetImage or SetImageFromBytes before Text or HOCRText")
+	}
+
+	if client.imageSet {
 		return nil
 	}
 
+	if err := client.wasm.setPixImage(client.api, client.pixImage, client.resolutionClamp); err != nil {
+		return err
+	}
+	if err := client.setSourceResolution(); err != nil {
+		return err
+	}
+	// TessBaseAPI::SetImage resets the rectangle to the whole image.
+	if !client.rectangle.Empty() {
+		if err := client.setRectangle(client.rectangle); err != nil {
+			return err
+		}
+	}
+	client.imageSet = true
+	return nil
+}
+
+// setSourceResolution overrides the DPI which SetPixImage took from the image.
+// The export is only called if SetSourceResolution found it.
+func (client *Client) setSourceResolution() error {
+	if client.sourceResolution == 0 {
+		return nil
+	}
+	_, err := client.wasm.SetSourceResolution(client.api, uint64(client.sourceResolution))
+	return err
+}
+
+func (client *Client) setRectangle(rect image.Rectangle) error {
+	_, err := client.wasm.SetRectangle(client.api, uint64(rect.Min.X), uint64(rect.Min.Y), uint64(rect.Dx()), uint64(rect.Dy()))
+	return err
+}
+
+// initAPI calls TessBaseAPI::Init with the languages, config file and tessdata prefix
+// of the client and sets the variables afterwards.
+func (client *Client) initAPI() error {
 	var languages string
 	if len(client.Languages) != 0 {
 		languages = strings.Join(client.Languages, "+")
 	}
-	languagesPtr := client.wasm.malloc(uint64(len(languages)) + 1)[0]
-	client.wasm.module.Memory().Write(uint32(languagesPtr), append([]byte(languages), 0))
+	languagesPtr, err := client.wasm.WriteString(languages)
+	if err != nil {
+		return err
+	}
 	defer client.wasm.free(languagesPtr)
 
 	var configFilePtr uint64
 	if client.ConfigFilePath != "" {
-		configFilePtr = client.wasm.malloc(uint64(len(client.ConfigFilePath) + 1))[0]
-		println(client.ConfigFilePath)
-		client.wasm.module.Memory().Write(uint32(configFilePtr), append([]byte(client.ConfigFilePath), 0))
+		configFilePath, err := client.wasm.sandbox.guestPath(client.ConfigFilePath)
+		if err != nil {
+			return err
+		}
+		configFilePtr, err = client.wasm.WriteString(configFilePath)
+		if err != nil {
+			return err
+		}
+		defer client.wasm.free(configFilePtr)
 	}
 
 	var tessdataPrefix string
 	if client.TessdataPrefix != "" {
-		tessdataPrefix = client.TessdataPrefix
+		if tessdataPrefix, err = client.wasm.sandbox.guestPath(client.TessdataPrefix); err != nil {
+			return err
+		}
 	} else {
 		tessdataPrefix = "/tessdata/"
 	}
-	tessdataPrefixPtr := client.wasm.malloc(uint64(len(tessdataPrefix) + 1))[0]
-
-	client.wasm.module.Memory().Write(uint32(tessdataPrefixPtr), append([]byte(tessdataPrefix), 0))
+	tessdataPrefixPtr, err := client.wasm.WriteString(tessdataPrefix)
+	if err != nil {
+		return err
+	}
 	defer client.wasm.free(tessdataPrefixPtr)
 
-	res := client.wasm.Init(client.api, tessdataPrefixPtr, languagesPtr, configFilePtr, 0)[0]
+	res, err := client.wasm.Init(client.api, tessdataPrefixPtr, languagesPtr, configFilePtr, uint64(client.ocrEngineMode))
+	if err != nil {
+		return err
+	}
 
-	if res != 0 {
+	if res[0] != 0 {
 		return fmt.Errorf("failed to initialize TessBaseAPI with code %d", -1)
 	}
 
@@ -311,12 +654,6 @@ func (client *Client) init() error {
 		return err
 	}
 
-	if client.pixImage == 0 {
-		return fmt.Errorf("PixImage is not set, use SetImage or SetImageFromBytes before Text or HOCRText")
-	}
-
-	client.wasm.SetPixImage(client.api, client.pixImage)
-
 	client.shouldInit = false
 
 	return nil
@@ -327,6 +664,7 @@ func (client *Client) init() error {
 // the instance needs to init a new gosseract api
 func (client *Client) flagForInit() {
 	client.shouldInit = true
+	client.imageSet = false
 }
 
 // This method sets all the sspecified variables to TessBaseAPI structure.
@@ -335,14 +673,21 @@ func (client *Client) flagForInit() {
 // See https://zdenop.github.io/tesseract-doc/classtesseract_1_1_tess_base_a_p_i.html#a2e09259c558c6d8e0f7e523cbaf5adf5
 func (client *Client) setVariablesToInitializedAPI() error {
 	for key, value := range client.Variables {
-		keyPtr := client.wasm.malloc(uint64(len(key) + 1))[0]
-		valPtr := client.wasm.malloc(uint64(len(key) + 1))[0]
-		client.wasm.module.Memory().Write(uint32(keyPtr), append([]byte(string(key)), 0))
-		client.wasm.module.Memory().Write(uint32(valPtr), append([]byte(string(value)), 0))
+		keyPtr, err := client.wasm.WriteString(string(key))
+		if err != nil {
+			return err
+		}
 		defer client.wasm.free(keyPtr)
+		valPtr, err := client.wasm.WriteString(value)
+		if err != nil {
+			return err
+		}
 		defer client.wasm.free(valPtr)
-		res := client.wasm.SetVariable(client.api, keyPtr, valPtr)[0]
-		if res == 0 {
+		res, err := client.wasm.SetVariable(client.api, keyPtr, valPtr)
+		if err != nil {
+			return err
+		}
+		if res[0] == 0 {
 			return fmt.Errorf("failed to set variable with key(%v) and value(%v)", key, value)
 		}
 	}
@@ -363,30 +708,205 @@ func (client *Client) setVariablesToInitializedAPIIfNeeded() error {
 
 // Text finally initialize tesseract::TessBaseAPI, execute OCR and extract text detected as string.
 func (client *Client) Text() (out string, err error) {
+	return client.TextContext(context.Background())
+}
+
+// TextContext is Text with a context.Context. When ctx is canceled or its deadline
+// is exceeded, recognition is aborted and ctx.Err() is returned.
+// The wasm module is closed by the abort, so the client cannot be used anymore
+// and every later call returns ErrClientClosed.
+func (client *Client) TextContext(ctx context.Context) (out string, err error) {
+	if err = client.useContext(ctx); err != nil {
+		return
+	}
+	defer client.wasm.useContext(ctx)()
 	if err = client.init(); err != nil {
 		return
 	}
-	resultPtr := client.wasm.Utf8Text(client.api)[0]
-	defer client.wasm.free(resultPtr)
-	out = client.wasm.ReadString(resultPtr)
+	res, err := client.wasm.Utf8Text(client.api)
+	if err != nil {
+		return
+	}
+	defer client.wasm.free(res[0])
+	if out, err = client.wasm.ReadString(res[0]); err != nil {
+		return
+	}
 	if client.Trim {
 		out = strings.Trim(out, "\n")
 	}
 	return out, err
 }
 
+// TextInRegions recognizes the text of each of regions, like Text with SetRectangle would.
+// The image is set once for all regions. The rectangle set by SetRectangle is ignored.
+func (client *Client) TextInRegions(regions []image.Rectangle) ([]string, error) {
+	return client.TextInRegionsContext(context.Background(), regions)
+}
+
+// TextInRegionsContext is TextInRegions with a context.Context, see TextContext for the cancellation behaviour.
+func (client *Client) TextInRegionsContext(ctx context.Context, regions []image.Rectangle) (out []string, err error) {
+	for _, rect := range regions {
+		if err = checkRectangle(rect); err != nil {
+			return nil, err
+		}
+	}
+	if err = client.useContext(ctx); err != nil {
+		return
+	}
+	defer client.wasm.useContext(ctx)()
+	if err = client.init(); err != nil {
+		return
+	}
+	// The rectangle of the client has to be set again afterwards.
+	defer func() { client.imageSet = false }()
+	out = make([]string, 0, len(regions))
+	for _, rect := range regions {
+		if err = client.setRectangle(rect); err != nil {
+			return nil, err
+		}
+		res, err := client.wasm.Utf8Text(client.api)
+		if err != nil {
+			return nil, err
+		}
+		text, err := client.wasm.ReadString(res[0])
+		client.wasm.free(res[0])
+		if err != nil {
+			return nil, err
+		}
+		if client.Trim {
+			text = strings.Trim(text, "\n")
+		}
+		out = append(out, text)
+	}
+	return out, nil
+}
+
+// MeanTextConf returns the mean confidence of the words, between 0 and 100.
+// Like the other methods reading results, e.g. HOCRText, GetBoundingBoxes or GetResults,
+// it reuses the last recognition, if neither the image nor the settings changed since,
+// so it's cheap to call after Text.
+func (client *Client) MeanTextConf() (int, error) {
+	confidences, err := client.AllWordConfidences()
+	if err != nil {
+		return 0, err
+	}
+	return meanTextConf(confidences), nil
+}
+
+// meanTextConf averages confidences like TessBaseAPI::MeanTextConf does.
+func meanTextConf(confidences []int) int {
+	if len(confidences) == 0 {
+		return 0
+	}
+	sum := 0
+	for _, confidence := range confidences {
+		sum += confidence
+	}
+	return sum / len(confidences)
+}
+
+// AllWordConfidences returns the confidence of every word, between 0 and 100,
+// in the order of GetBoundingBoxes(RIL_WORD). See MeanTextConf for the reuse of the recognition.
+func (client *Client) AllWordConfidences() ([]int, error) {
+	if err := client.init(); err != nil {
+		return nil, err
+	}
+	return client.allWordConfidences()
+}
+
+func (client *Client) allWordConfidences() ([]int, error) {
+	res, err := client.wasm.AllWordConfidences(client.api)
+	if err != nil {
+		return nil, err
+	}
+	if res[0] == 0 {
+		return nil, fmt.Errorf("failed to recognize the image")
+	}
+	defer client.wasm.free(res[0])
+	mem := client.wasm.module.Memory()
+	var confidences []int
+	for ptr := uint32(res[0]); ; ptr += 4 {
+		x, ok := mem.ReadUint32Le(ptr)
+		if !ok {
+			return nil, &WasmError{Func: "AllWordConfidences", Kind: ErrWasmTrap, Err: fmt.Errorf("pointer %d is out of range", ptr)}
+		}
+		// The array is terminated by -1.
+		if int32(x) < 0 {
+			return confidences, nil
+		}
+		confidences = append(confidences, int(int32(x)))
+	}
+}
+
+// TextResult is the text of the image along with its confidence, see TextWithConfidence.
+type TextResult struct {
+	Text string
+	// MeanConfidence is the mean of WordConfidences, see MeanTextConf.
+	MeanConfidence  int
+	WordConfidences []int
+}
+
+// TextWithConfidence is Text, which also returns the confidence of the same recognition.
+func (client *Client) TextWithConfidence() (TextResult, error) {
+	return client.TextWithConfidenceContext(context.Background())
+}
+
+// TextWithConfidenceContext is TextWithConfidence with a context.Context, see TextContext for the cancellation behaviour.
+func (client *Client) TextWithConfidenceContext(ctx context.Context) (result TextResult, err error) {
+	if result.Text, err = client.TextContext(ctx); err != nil {
+		return
+	}
+	defer client.wasm.useContext(ctx)()
+	if result.WordConfidences, err = client.allWordConfidences(); err != nil {
+		return
+	}
+	result.MeanConfidence = meanTextConf(result.WordConfidences)
+	return result, nil
+}
+
 // HOCRText finally initialize tesseract::TessBaseAPI, execute OCR and returns hOCR text.
 // See https://en.wikipedia.org/wiki/HOCR for more information of hOCR.
 func (client *Client) HOCRText() (out string, err error) {
+	return client.HOCRTextContext(context.Background())
+}
+
+// HOCRTextContext is HOCRText with a context.Context, see TextContext for the cancellation behaviour.
+func (client *Client) HOCRTextContext(ctx context.Context) (out string, err error) {
+	if err = client.useContext(ctx); err != nil {
+		return
+	}
+	defer client.wasm.useContext(ctx)()
 	if err = client.init(); err != nil {
 		return
 	}
-	textPtr := client.wasm.HocrText(client.api)[0]
-	defer client.wasm.free(textPtr)
-	out = client.wasm.ReadString(textPtr)
+	res, err := client.wasm.HocrText(client.api)
+	if err != nil {
+		return
+	}
+	defer client.wasm.free(res[0])
+	out, err = client.wasm.ReadString(res[0])
 	return
 }
 
+// useContext checks if the client can run a call with ctx at all.
+func (client *Client) useContext(ctx context.Context) error {
+	if err := client.checkAPI(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// checkAPI reports ErrClientClosed after Close, so no method touches the released wasm module.
+func (client *Client) checkAPI() error {
+	if client.closed {
+		return ErrClientClosed
+	}
+	if client.api == 0 {
+		return fmt.Errorf("TessBaseAPI is not constructed, please use `gosseract.NewClient`")
+	}
+	return nil
+}
+
 // BoundingBox contains the position, confidence and UTF8 text of the recognized word
 type BoundingBox struct {
 	Box                                image.Rectangle
@@ -397,46 +917,23 @@ type BoundingBox struct {
 
 // GetBoundingBoxes returns bounding boxes for each matched word
 func (client *Client) GetBoundingBoxes(level PageIteratorLevel) (out []BoundingBox, err error) {
-	if client.api == 0 {
-		return out, fmt.Errorf("TessBaseAPI is not constructed, please use `gosseract.NewClient`")
+	return client.GetBoundingBoxesContext(context.Background(), level)
+}
+
+// GetBoundingBoxesContext is GetBoundingBoxes with a context.Context, see TextContext for the cancellation behaviour.
+func (client *Client) GetBoundingBoxesContext(ctx context.Context, level PageIteratorLevel) (out []BoundingBox, err error) {
+	if err = client.useContext(ctx); err != nil {
+		return
 	}
+	defer client.wasm.useContext(ctx)()
 	if err = client.init(); err != nil {
 		return
 	}
-	boundingBoxesPtr := client.wasm.GetBoundingBoxes(client.api, uint64(level))[0]
-	defer client.wasm.free(boundingBoxesPtr)
-	length, _ := client.wasm.module.Memory().ReadUint32Le(uint32(boundingBoxesPtr))
-	boxArrayPtr, _ := client.wasm.module.Memory().ReadUint64Le(uint32(boundingBoxesPtr) + 4)
-	defer client.wasm.free(boxArrayPtr)
-
-	readInt := func(base uint64, offset int) int {
-		x, _ := client.wasm.module.Memory().ReadUint32Le(uint32(base) + uint32(offset))
-		return int(x)
-	}
-
-	readFloat64 := func(base uint64, offset int) float64 {
-		x, _ := client.wasm.module.Memory().ReadFloat64Le(uint32(base) + uint32(offset))
-		return x
-	}
-
-	out = make([]BoundingBox, 0, length)
-
-	for i := 0; i < int(length); i++ {
-		x1 := readInt(boxArrayPtr, 48*i)
-		y1 := readInt(boxArrayPtr, 48*i+4)
-		x2 := readInt(boxArrayPtr, 48*i+8)
-		y2 := readInt(boxArrayPtr, 48*i+12)
-		wordPtr, _ := client.wasm.module.Memory().ReadUint32Le(uint32(boxArrayPtr) + uint32(48*i+16))
-		word := client.wasm.ReadString(uint64(wordPtr))
-		confidence := readFloat64(boxArrayPtr, 48*i+24)
-		out = append(out, BoundingBox{
-			Box:        image.Rect(x1, y1, x2, y2),
-			Word:       word,
-			Confidence: confidence,
-		})
+	res, err := client.wasm.GetBoundingBoxes(client.api, uint64(level))
+	if err != nil {
+		return
 	}
-
-	return
+	return client.readBoundingBoxes(res[0], false)
 }
 
 // GetAvailableLanguages returns a list of available languages in the default tesspath
@@ -456,64 +953,85 @@ func GetAvailableLanguages() ([]string, error) {
 // GetBoundingBoxesVerbose returns bounding boxes at word level with block_num, par_num, line_num and word_num
 // according to the c++ api that returns a formatted TSV output. Reference: `TessBaseAPI::GetTSVText`.
 func (client *Client) GetBoundingBoxesVerbose() (out []BoundingBox, err error) {
-	if client.api == 0 {
-		return out, fmt.Errorf("TessBaseAPI is not constructed, please use `gosseract.NewClient`")
+	if err = client.checkAPI(); err != nil {
+		return
 	}
 	if err = client.init(); err != nil {
 		return
 	}
-	boundingBoxesPtr := client.wasm.GetBoundingBoxesVerbose(client.api)[0]
+	res, err := client.wasm.GetBoundingBoxesVerbose(client.api)
+	if err != nil {
+		return
+	}
+	return client.readBoundingBoxes(res[0], true)
+}
+
+// readBoundingBoxes copies a `struct bounding_boxes` out of the module memory and frees it.
+// The block, par, line and word numbers are only filled by GetBoundingBoxesVerbose.
+func (client *Client) readBoundingBoxes(boundingBoxesPtr uint64, verbose bool) (out []BoundingBox, err error) {
+	// boxSize is sizeof(struct bounding_box) in wasm32, see tessbridge.h.
+	const boxSize = 40
+	mem := client.wasm.module.Memory()
 	defer client.wasm.free(boundingBoxesPtr)
-	length, _ := client.wasm.module.Memory().ReadUint32Le(uint32(boundingBoxesPtr))
-	boxArrayPtr, _ := client.wasm.module.Memory().ReadUint64Le(uint32(boundingBoxesPtr) + 4)
-	defer client.wasm.free(boxArrayPtr)
+	length, ok := mem.ReadUint32Le(uint32(boundingBoxesPtr))
+	if !ok {
+		return nil, &WasmError{Func: "GetBoundingBoxes", Kind: ErrWasmTrap, Err: fmt.Errorf("pointer %d is out of range", boundingBoxesPtr)}
+	}
+	boxArrayPtr, _ := mem.ReadUint32Le(uint32(boundingBoxesPtr) + 4)
+	defer client.wasm.free(uint64(boxArrayPtr))
 
-	readInt := func(base uint64, offset int) int {
-		x, _ := client.wasm.module.Memory().ReadUint32Le(uint32(base) + uint32(offset))
-		return int(x)
+	readInt := func(offset int) int {
+		x, _ := mem.ReadUint32Le(boxArrayPtr + uint32(offset))
+		return int(int32(x))
 	}
 
-	readFloat64 := func(base uint64, offset int) float64 {
-		x, _ := client.wasm.module.Memory().ReadFloat64Le(uint32(base) + uint32(offset))
-		return x
+	readFloat32 := func(offset int) float64 {
+		x, _ := mem.ReadFloat32Le(boxArrayPtr + uint32(offset))
+		return float64(x)
 	}
 
 	out = make([]BoundingBox, 0, length)
 
 	for i := 0; i < int(length); i++ {
-		x1 := readInt(boxArrayPtr, 48*i)
-		y1 := readInt(boxArrayPtr, 48*i+4)
-		x2 := readInt(boxArrayPtr, 48*i+8)
-		y2 := readInt(boxArrayPtr, 48*i+12)
-		wordPtr, _ := client.wasm.module.Memory().ReadUint32Le(uint32(boxArrayPtr) + uint32(48*i+16))
-		word := client.wasm.ReadString(uint64(wordPtr))
-		confidence := readFloat64(boxArrayPtr, 48*i+24)
-		blockNum := readInt(boxArrayPtr, 48*i+32)
-		parNum := readInt(boxArrayPtr, 48*i+36)
-		lineNum := readInt(boxArrayPtr, 48*i+40)
-		wordNum := readInt(boxArrayPtr, 48*i+44)
-
-		out = append(out, BoundingBox{
-			Box:        image.Rect(x1, y1, x2, y2),
+		base := boxSize * i
+		wordPtr := uint64(readInt(base + 16))
+		word, err := client.wasm.ReadString(wordPtr)
+		if err != nil {
+			return nil, err
+		}
+		if wordPtr != 0 {
+			client.wasm.free(wordPtr)
+		}
+		box := BoundingBox{
+			Box:        image.Rect(readInt(base), readInt(base+4), readInt(base+8), readInt(base+12)),
 			Word:       word,
-			Confidence: confidence,
-			BlockNum:   blockNum,
-			ParNum:     parNum,
-			LineNum:    lineNum,
-			WordNum:    wordNum,
-		})
+			Confidence: readFloat32(base + 20),
+		}
+		if verbose {
+			box.BlockNum = readInt(base + 24)
+			box.ParNum = readInt(base + 28)
+			box.LineNum = readInt(base + 32)
+			box.WordNum = readInt(base + 36)
+		}
+		out = append(out, box)
 	}
 
-	return
+	return out, nil
 }
 
 // getDataPath is useful hepler to determine where current tesseract
 // installation stores trained models
 func getDataPath() string {
-	wasm := newApi()
+	wasm, err := newApi()
+	if err != nil {
+		return ""
+	}
 	defer wasm.Close()
-	dataPathPtr := wasm.GetDataPath()[0]
-	dataPath := wasm.ReadString(dataPathPtr)
-	defer wasm.free(dataPathPtr)
+	res, err := wasm.GetDataPath()
+	if err != nil {
+		return ""
+	}
+	// GetDataPath returns a string owned by a static TessBaseAPI, it must not be freed.
+	dataPath, _ := wasm.ReadString(res[0])
 	return dataPath
 }
@@ -39,6 +39,26 @@ const (
 	PSM_COUNT
 )
 
+// OcrEngineMode represents tesseract::OcrEngineMode.
+// See https://github.com/tesseract-ocr/tesseract/blob/080da83cc51c4ef8b324a7e03146fe0bd7e0944b/include/tesseract/publictypes.h#L265-L276 for more information.
+type OcrEngineMode int
+
+const (
+	// OEM_TESSERACT_ONLY - Run the legacy Tesseract engine only.
+	OEM_TESSERACT_ONLY OcrEngineMode = iota
+	// OEM_LSTM_ONLY - Run just the LSTM line recognizer.
+	OEM_LSTM_ONLY
+	// OEM_TESSERACT_LSTM_COMBINED - Run the LSTM recognizer, but allow fallback to the legacy engine.
+	OEM_TESSERACT_LSTM_COMBINED
+	// OEM_DEFAULT - (DEFAULT) Specify this mode to indicate that any of the above modes should be
+	// automatically inferred from the variables in the language-specific config, command-line configs,
+	// or if not specified in any of the above should be set to the default OEM_TESSERACT_ONLY.
+	OEM_DEFAULT
+
+	// OEM_COUNT - Just a number of enum entries. This is NOT a member of OEM ;)
+	OEM_COUNT
+)
+
 // PageIteratorLevel maps directly to tesseracts enum tesseract::PageIteratorLevel
 // represents the hierarchy of the page elements used in ResultIterator.
 // https://github.com/tesseract-ocr/tesseract/blob/a18620cfea33d03032b71fe1b9fc424777e34252/ccstruct/publictypes.h#L219-L225
@@ -57,6 +77,119 @@ const (
 	RIL_SYMBOL
 )
 
+// PolyBlockType maps directly to tesseracts enum PolyBlockType,
+// the kind of content of a block found by the layout analysis.
+type PolyBlockType int
+
+const (
+	// PT_UNKNOWN - Type is not yet known. Keep as the first element.
+	PT_UNKNOWN PolyBlockType = iota
+	// PT_FLOWING_TEXT - Text that lives inside a column.
+	PT_FLOWING_TEXT
+	// PT_HEADING_TEXT - Text that spans more than one column.
+	PT_HEADING_TEXT
+	// PT_PULLOUT_TEXT - Text that is in a cross-column pull-out region.
+	PT_PULLOUT_TEXT
+	// PT_EQUATION - Partition belonging to an equation region.
+	PT_EQUATION
+	// PT_INLINE_EQUATION - Partition has inline equation.
+	PT_INLINE_EQUATION
+	// PT_TABLE - Partition belonging to a table region.
+	PT_TABLE
+	// PT_VERTICAL_TEXT - Text-line runs vertically.
+	PT_VERTICAL_TEXT
+	// PT_CAPTION_TEXT - Text that belongs to an image.
+	PT_CAPTION_TEXT
+	// PT_FLOWING_IMAGE - Image that lives inside a column.
+	PT_FLOWING_IMAGE
+	// PT_HEADING_IMAGE - Image that spans more than one column.
+	PT_HEADING_IMAGE
+	// PT_PULLOUT_IMAGE - Image that is in a cross-column pull-out region.
+	PT_PULLOUT_IMAGE
+	// PT_HORZ_LINE - Horizontal Line.
+	PT_HORZ_LINE
+	// PT_VERT_LINE - Vertical Line.
+	PT_VERT_LINE
+	// PT_NOISE - Lies outside of any column.
+	PT_NOISE
+	// PT_COUNT - Just a number of enum entries.
+	PT_COUNT
+)
+
+// IsText reports whether blocks of this type contain text.
+func (t PolyBlockType) IsText() bool {
+	switch t {
+	case PT_FLOWING_TEXT, PT_HEADING_TEXT, PT_PULLOUT_TEXT, PT_TABLE, PT_VERTICAL_TEXT, PT_CAPTION_TEXT, PT_INLINE_EQUATION:
+		return true
+	}
+	return false
+}
+
+// IsImage reports whether blocks of this type are images.
+func (t PolyBlockType) IsImage() bool {
+	return t == PT_FLOWING_IMAGE || t == PT_HEADING_IMAGE || t == PT_PULLOUT_IMAGE
+}
+
+// IsLine reports whether blocks of this type are separator lines.
+func (t PolyBlockType) IsLine() bool {
+	return t == PT_HORZ_LINE || t == PT_VERT_LINE
+}
+
+// Orientation maps directly to tesseracts enum tesseract::Orientation,
+// the direction the top of the text points to.
+type Orientation int
+
+const (
+	// ORIENTATION_PAGE_UP - Upright text.
+	ORIENTATION_PAGE_UP Orientation = iota
+	// ORIENTATION_PAGE_RIGHT - Text rotated by 90 degrees clockwise.
+	ORIENTATION_PAGE_RIGHT
+	// ORIENTATION_PAGE_DOWN - Text upside down.
+	ORIENTATION_PAGE_DOWN
+	// ORIENTATION_PAGE_LEFT - Text rotated by 90 degrees counterclockwise.
+	ORIENTATION_PAGE_LEFT
+)
+
+// WritingDirection maps directly to tesseracts enum tesseract::WritingDirection,
+// the direction of the characters within a line, when the text is upright.
+type WritingDirection int
+
+const (
+	// WRITING_DIRECTION_LEFT_TO_RIGHT - e.g. Latin scripts.
+	WRITING_DIRECTION_LEFT_TO_RIGHT WritingDirection = iota
+	// WRITING_DIRECTION_RIGHT_TO_LEFT - e.g. Arabic and Hebrew.
+	WRITING_DIRECTION_RIGHT_TO_LEFT
+	// WRITING_DIRECTION_TOP_TO_BOTTOM - e.g. vertical Chinese and Japanese.
+	WRITING_DIRECTION_TOP_TO_BOTTOM
+)
+
+// TextlineOrder maps directly to tesseracts enum tesseract::TextlineOrder,
+// the order of the lines within a block, when the text is upright.
+type TextlineOrder int
+
+const (
+	// TEXTLINE_ORDER_LEFT_TO_RIGHT - e.g. vertical Mongolian.
+	TEXTLINE_ORDER_LEFT_TO_RIGHT TextlineOrder = iota
+	// TEXTLINE_ORDER_RIGHT_TO_LEFT - e.g. vertical Chinese and Japanese.
+	TEXTLINE_ORDER_RIGHT_TO_LEFT
+	// TEXTLINE_ORDER_TOP_TO_BOTTOM - Horizontal text.
+	TEXTLINE_ORDER_TOP_TO_BOTTOM
+)
+
+// ParagraphJustification maps directly to tesseracts enum tesseract::ParagraphJustification.
+type ParagraphJustification int
+
+const (
+	// JUSTIFICATION_UNKNOWN - The alignment is not clear.
+	JUSTIFICATION_UNKNOWN ParagraphJustification = iota
+	// JUSTIFICATION_LEFT - Lines aligned at the left, or fully justified left to right text.
+	JUSTIFICATION_LEFT
+	// JUSTIFICATION_CENTER - Centered lines.
+	JUSTIFICATION_CENTER
+	// JUSTIFICATION_RIGHT - Lines aligned at the right, or fully justified right to left text.
+	JUSTIFICATION_RIGHT
+)
+
 // SettableVariable represents available strings for TessBaseAPI::SetVariable.
 // See https://groups.google.com/forum/#!topic/tesseract-ocr/eHTBzrBiwvQ
 // and https://github.com/tesseract-ocr/tesseract/blob/master/src/ccmain/tesseractclass.h
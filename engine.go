@@ -0,0 +1,195 @@
+package gosseract
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/emscripten"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// EngineConfig configures an Engine.
+type EngineConfig struct {
+	// CompilationCacheDir keeps the compiled wasm on disk, see SetCompilationCacheDir.
+	// It's ignored if RuntimeConfig or Runtime is given.
+	CompilationCacheDir string
+
+	// RuntimeConfig is used to create the wazero runtime. If not specified, it's a
+	// default config with CloseOnContextDone enabled, which the *Context methods rely on.
+	RuntimeConfig wazero.RuntimeConfig
+
+	// Runtime is a custom wazero runtime to compile the module in. It's not closed by
+	// Engine.Close, as it is owned by the caller. The host modules tesseract imports
+	// ("wasi_snapshot_preview1" and "env") are instantiated, unless the runtime has them already.
+	Runtime wazero.Runtime
+}
+
+// Engine owns the wazero runtime and the compiled tesseract module, which clients are
+// instantiated from. NewClient and friends use a default engine, which is never closed.
+// An Engine can be used to release the compiled code, e.g. in tests or long running hosts.
+type Engine struct {
+	runtime wazero.Runtime
+	module  wazero.CompiledModule
+	// config is used for clients which need a runtime of their own, see ClientOptions.MemoryLimit.
+	config wazero.RuntimeConfig
+	// ownsRuntime is false if the runtime was given by EngineConfig.Runtime.
+	ownsRuntime bool
+
+	mu sync.Mutex
+	// closed is set by Close, no clients are created afterwards.
+	closed bool
+	// clientRuntimes are the runtimes of clients with a memory limit, to close them along with the engine.
+	clientRuntimes map[wazero.Runtime]struct{}
+}
+
+// errEngineClosed is reported when a client is created from a closed engine.
+var errEngineClosed = fmt.Errorf("engine is closed")
+
+// NewEngine compiles the embedded tesseract module. It's due to caller to Close this engine.
+func NewEngine(cfg EngineConfig) (*Engine, error) {
+	config := cfg.RuntimeConfig
+	if config == nil {
+		cache := wazero.NewCompilationCache()
+		if cfg.CompilationCacheDir != "" {
+			var err error
+			if cache, err = wazero.NewCompilationCacheWithDir(cfg.CompilationCacheDir); err != nil {
+				return nil, fmt.Errorf("failed to open compilation cache dir: %w", err)
+			}
+		}
+		config = newRuntimeConfig(cache)
+	}
+
+	engine := &Engine{config: config, runtime: cfg.Runtime}
+	if engine.runtime == nil {
+		// Create a new WebAssembly Runtime.
+		engine.runtime = wazero.NewRuntimeWithConfig(ctx, config)
+		engine.ownsRuntime = true
+	}
+	if err := engine.compile(); err != nil {
+		engine.Close()
+		return nil, err
+	}
+	return engine, nil
+}
+
+// compile compiles the embedded module and instantiates its imports.
+func (engine *Engine) compile() error {
+	if engine.runtime.Module(wasi_snapshot_preview1.ModuleName) == nil {
+		if _, err := wasi_snapshot_preview1.Instantiate(ctx, engine.runtime); err != nil {
+			return fmt.Errorf("failed to instantiate wasi: %w", err)
+		}
+	}
+
+	module, err := engine.runtime.CompileModule(ctx, binary)
+	if err != nil {
+		return fmt.Errorf("failed to compile module: %w", err)
+	}
+	engine.module = module
+
+	if engine.runtime.Module("env") == nil {
+		if _, err := emscripten.InstantiateForModule(ctx, engine.runtime, module); err != nil {
+			return fmt.Errorf("failed to instantiate module (emscripten): %w", err)
+		}
+	}
+	return nil
+}
+
+// Close releases the compiled module and the runtime. Clients created by this engine
+// are closed along with it and cannot be used anymore, including the ones with a runtime
+// of their own because of ClientOptions.MemoryLimit.
+func (engine *Engine) Close() error {
+	engine.mu.Lock()
+	engine.closed = true
+	runtimes := engine.clientRuntimes
+	engine.clientRuntimes = nil
+	module, runtime := engine.module, engine.runtime
+	engine.module, engine.runtime = nil, nil
+	engine.mu.Unlock()
+
+	var err error
+	for runtime := range runtimes {
+		if rerr := runtime.Close(ctx); err == nil {
+			err = rerr
+		}
+	}
+	if module != nil {
+		if rerr := module.Close(ctx); err == nil {
+			err = rerr
+		}
+	}
+	if engine.ownsRuntime && runtime != nil {
+		if rerr := runtime.Close(ctx); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// NewClient construct new Client from this engine, configured by opts like New.
+// It's due to caller to Close this client.
+func (engine *Engine) NewClient(opts ...Option) (*Client, error) {
+	return New(append([]Option{WithEngine(engine)}, opts...)...)
+}
+
+// newApi instantiates the compiled module for a client.
+func (engine *Engine) newApi(opts ClientOptions) (*tesseractApi, error) {
+	engine.mu.Lock()
+	closed, runtime, module := engine.closed, engine.runtime, engine.module
+	engine.mu.Unlock()
+	if closed {
+		return nil, errEngineClosed
+	}
+	sandbox, err := newSandbox(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var ownRuntime wazero.Runtime
+	if opts.MemoryLimit != 0 {
+		if err := checkMemoryLimit(opts.MemoryLimit); err != nil {
+			return nil, err
+		}
+		// The memory limit can only be set per runtime, so this client gets its own.
+		// Compiling again is cheap thanks to the shared compilation cache.
+		pages := uint32(opts.MemoryLimit / wasmPageSize)
+		own, err := NewEngine(EngineConfig{RuntimeConfig: engine.config.WithMemoryLimitPages(pages)})
+		if err != nil {
+			return nil, err
+		}
+		runtime, module, ownRuntime = own.runtime, own.module, own.runtime
+	}
+
+	mod, err := runtime.InstantiateModule(ctx, module, wazero.NewModuleConfig().
+		WithStartFunctions("_initialize").
+		WithFSConfig(sandbox.fsConfig()))
+
+	if err != nil {
+		if ownRuntime != nil {
+			ownRuntime.Close(ctx)
+		}
+		return nil, fmt.Errorf("failed to instantiate module: %w", err)
+	}
+	tAPI, err := newTesseractApi(mod, ownRuntime, sandbox)
+	if err != nil || ownRuntime == nil {
+		return tAPI, err
+	}
+	engine.mu.Lock()
+	if engine.closed {
+		// Close missed this runtime, as it was not tracked yet.
+		engine.mu.Unlock()
+		tAPI.Close()
+		return nil, errEngineClosed
+	}
+	if engine.clientRuntimes == nil {
+		engine.clientRuntimes = map[wazero.Runtime]struct{}{}
+	}
+	engine.clientRuntimes[ownRuntime] = struct{}{}
+	engine.mu.Unlock()
+	tAPI.release = func() {
+		engine.mu.Lock()
+		delete(engine.clientRuntimes, ownRuntime)
+		engine.mu.Unlock()
+	}
+	return tAPI, nil
+}
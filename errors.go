@@ -0,0 +1,70 @@
+package gosseract
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrWasmTrap is reported when the tesseract wasm module traps, e.g. by an
+	// out of bounds memory access or an `unreachable` instruction inside leptonica.
+	// The module state cannot be trusted afterwards, so the client should be closed.
+	ErrWasmTrap = errors.New("wasm module trapped")
+
+	// ErrOutOfMemory is reported when the wasm module cannot allocate memory.
+	ErrOutOfMemory = errors.New("wasm module is out of memory")
+
+	// ErrMissingExport is reported when the embedded wasm binary does not export
+	// a function gosseract expects. Rebuilding build/tesseract-core.wasm fixes this.
+	ErrMissingExport = errors.New("wasm module does not export function")
+
+	// ErrClientClosed is reported when a method is called on a closed client.
+	ErrClientClosed = errors.New("client is closed")
+
+	// ErrPathNotAllowed is reported when a path is outside of the filesystem
+	// exposed by ClientOptions.
+	ErrPathNotAllowed = errors.New("path is not exposed to tesseract")
+
+	// ErrImageTooLarge is reported when image data exceeds the given limit.
+	ErrImageTooLarge = errors.New("image is too large")
+
+	// ErrUnsupportedImageFormat is reported when image data is in a format
+	// leptonica in the wasm build cannot decode.
+	ErrUnsupportedImageFormat = errors.New("unsupported image format")
+
+	// ErrImageDecode is reported when leptonica cannot decode image data
+	// in a supported format, e.g. because it is truncated.
+	ErrImageDecode = errors.New("could not decode image")
+
+	// ErrLegacyEngineDisabled is reported when a legacy OCR engine mode is requested,
+	// but tesseract was built with DISABLED_LEGACY_ENGINE=ON.
+	ErrLegacyEngineDisabled = errors.New("legacy OCR engine is disabled in this build")
+)
+
+// WasmError describes a failure while calling into the tesseract wasm module.
+// Kind is one of the sentinel errors above and can be checked with `errors.Is`.
+type WasmError struct {
+	// Func is the name of the wasm function which was called.
+	Func string
+	// Kind is the sentinel error, such as ErrWasmTrap or ErrOutOfMemory.
+	Kind error
+	// Err is the underlying error returned by wazero, if any.
+	Err error
+}
+
+func (e *WasmError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%v: %s", e.Kind, e.Func)
+	}
+	return fmt.Sprintf("%v: %s: %v", e.Kind, e.Func, e.Err)
+}
+
+// Is allows `errors.Is(err, ErrWasmTrap)` and friends.
+func (e *WasmError) Is(target error) bool {
+	return e.Kind == target
+}
+
+// Unwrap returns the underlying wazero error.
+func (e *WasmError) Unwrap() error {
+	return e.Err
+}
@@ -6,13 +6,29 @@ import (
 )
 
 func ExampleNewClient() {
-	client := NewClient()
+	client, err := NewClient()
+	if err != nil {
+		// The wasm module could not be loaded.
+		panic(err)
+	}
 	// Never forget to defer Close. It is due to caller to Close this client.
 	defer client.Close()
 }
 
+func ExampleNew() {
+	client, err := New(
+		WithLanguages("eng"),
+		WithPageSegMode(PSM_SINGLE_BLOCK),
+	)
+	if err != nil {
+		// The configuration is invalid, or the wasm module could not be loaded.
+		panic(err)
+	}
+	defer client.Close()
+}
+
 func ExampleClient_SetImage() {
-	client := NewClient()
+	client, _ := NewClient()
 	defer client.Close()
 
 	client.SetImage("./test/data/001-helloworld.png")
@@ -21,7 +37,7 @@ func ExampleClient_SetImage() {
 
 func ExampleClient_Text() {
 
-	client := NewClient()
+	client, _ := NewClient()
 	defer client.Close()
 
 	client.SetImage("./test/data/001-helloworld.png")
@@ -36,7 +52,7 @@ func ExampleClient_SetWhitelist() {
 		os.Exit(0)
 	}
 
-	client := NewClient()
+	client, _ := NewClient()
 	defer client.Close()
 	client.SetImage("./test/data/002-confusing.png")
 
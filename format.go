@@ -0,0 +1,82 @@
+package gosseract
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// imageFormat is the file format of an image, detected from its first bytes.
+type imageFormat string
+
+// Formats known by detectImageFormat.
+const (
+	formatUnknown imageFormat = "unknown"
+	formatPNG     imageFormat = "PNG"
+	formatJPEG    imageFormat = "JPEG"
+	formatBMP     imageFormat = "BMP"
+	formatPNM     imageFormat = "PNM"
+	formatTIFF    imageFormat = "TIFF"
+	formatGIF     imageFormat = "GIF"
+	formatWebP    imageFormat = "WebP"
+	formatJP2     imageFormat = "JPEG 2000"
+	formatPDF     imageFormat = "PDF"
+)
+
+// sniffLen is the number of bytes detectImageFormat needs at most.
+const sniffLen = 12
+
+// detectImageFormat detects the format of an image from the first sniffLen bytes of its data.
+func detectImageFormat(head []byte) imageFormat {
+	switch {
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		return formatPNG
+	case bytes.HasPrefix(head, []byte("\xff\xd8\xff")):
+		return formatJPEG
+	case bytes.HasPrefix(head, []byte("BM")):
+		return formatBMP
+	case len(head) >= 2 && head[0] == 'P' && head[1] >= '1' && head[1] <= '7':
+		return formatPNM
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		return formatTIFF
+	case bytes.HasPrefix(head, []byte("GIF87a")), bytes.HasPrefix(head, []byte("GIF89a")):
+		return formatGIF
+	case len(head) >= 12 && bytes.HasPrefix(head, []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WEBP")):
+		return formatWebP
+	case bytes.HasPrefix(head, []byte("\x00\x00\x00\x0cjP  ")), bytes.HasPrefix(head, []byte("\xff\x4f\xff\x51")):
+		return formatJP2
+	case bytes.HasPrefix(head, []byte("%PDF")):
+		return formatPDF
+	}
+	return formatUnknown
+}
+
+// supported reports whether leptonica in the wasm build can decode the format.
+// It is built with zlib, libpng and libjpeg only, see EMCC_FLAGS in the Makefile.
+func (format imageFormat) supported() bool {
+	switch format {
+	case formatPNG, formatJPEG, formatBMP, formatPNM:
+		return true
+	}
+	return false
+}
+
+// checkImageFormat rejects image data starting with head, if leptonica cannot decode its format.
+// This has to be checked before the data is handed to leptonica, which exits the whole
+// module on some formats it was built without, e.g. GIF.
+func checkImageFormat(head []byte) error {
+	if format := detectImageFormat(head); !format.supported() {
+		return fmt.Errorf("%w: %s", ErrUnsupportedImageFormat, format)
+	}
+	return nil
+}
+
+// decodeImageError describes why leptonica could not decode the image data starting with head,
+// which passed checkImageFormat. Leptonica either returns NULL, or for some errors of libpng and
+// libjpeg exits the module, which is given as err.
+func decodeImageError(head []byte, err error) error {
+	format := detectImageFormat(head)
+	if err != nil {
+		return fmt.Errorf("%w: %s data is corrupted, the client cannot be used anymore: %v", ErrImageDecode, format, err)
+	}
+	return fmt.Errorf("%w: %s data is corrupted", ErrImageDecode, format)
+}
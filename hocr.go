@@ -1,13 +1,25 @@
 package gosseract
 
+import (
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
 /**
  * NOTE:
  * 	These structs are the very minimum implementation
  *	only to satisfy test assertions.
- * TODO: Extend structs to cover main usecases.
+ *	They assume a single carea and paragraph, see ParseHOCR for the full model.
 **/
 
 // Page represents `<div class='ocr_page' />`
+//
+// Deprecated: Use ParseHOCR, which handles any number of areas and paragraphs.
 type Page struct {
 	ID      string  `xml:"id,attr"`
 	Title   string  `xml:"title,attr"`
@@ -47,3 +59,410 @@ type Word struct {
 	Class      string `xml:"class,attr"`
 	Characters string `xml:",chardata"`
 }
+
+// HOCRDocument is a parsed hOCR document, see ParseHOCR.
+type HOCRDocument struct {
+	Pages []*HOCRPage
+}
+
+// HOCRElement holds the attributes every hOCR element has.
+type HOCRElement struct {
+	Class string    `xml:"class,attr"`
+	ID    string    `xml:"id,attr,omitempty"`
+	Lang  string    `xml:"lang,attr,omitempty"`
+	Dir   string    `xml:"dir,attr,omitempty"`
+	Title HOCRTitle `xml:"title,attr,omitempty"`
+}
+
+// HOCRPage represents `<div class='ocr_page' />`.
+type HOCRPage struct {
+	HOCRElement
+	// Areas are the `ocr_carea` divs, and the divs of non-text blocks like `ocr_photo` and `ocr_separator`.
+	Areas []*HOCRArea `xml:"div"`
+}
+
+// HOCRArea represents `<div class='ocr_carea' />`.
+type HOCRArea struct {
+	HOCRElement
+	Paragraphs []*HOCRParagraph `xml:"p"`
+}
+
+// HOCRParagraph represents `<p class='ocr_par' />`.
+type HOCRParagraph struct {
+	HOCRElement
+	// Lines are the `ocr_line`, `ocr_caption`, `ocr_header` and `ocr_textfloat` spans.
+	Lines []*HOCRLine `xml:"span"`
+}
+
+// HOCRLine represents `<span class='ocr_line' />` and the other kinds of lines, told apart by Class.
+type HOCRLine struct {
+	HOCRElement
+	Words []*HOCRWord `xml:"span"`
+}
+
+// HOCRWord represents `<span class='ocrx_word' />`.
+type HOCRWord struct {
+	HOCRElement
+	Text string
+	// Bold and Italic are set, if the text is wrapped by `<strong>` and `<em>`, which
+	// tesseract does for the fonts found by the legacy engine.
+	Bold, Italic bool
+	// Chars are only given, if the variable hocr_char_boxes was set.
+	Chars []*HOCRChar
+}
+
+// HOCRChar represents `<span class='ocrx_cinfo' />`.
+type HOCRChar struct {
+	HOCRElement
+	Text string `xml:",chardata"`
+}
+
+// ParseHOCR parses the hOCR of r, which is either a complete document as written by the
+// tesseract command line, or the `ocr_page` divs returned by HOCRText.
+func ParseHOCR(r io.Reader) (*HOCRDocument, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	doc := &HOCRDocument{}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return doc, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse hOCR: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || !hasClass(start, "ocr_page") {
+			continue
+		}
+		page := &HOCRPage{}
+		if err := decoder.DecodeElement(page, &start); err != nil {
+			return nil, fmt.Errorf("failed to parse hOCR: %w", err)
+		}
+		doc.Pages = append(doc.Pages, page)
+	}
+}
+
+func hasClass(start xml.StartElement, class string) bool {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "class" {
+			for _, c := range strings.Fields(attr.Value) {
+				if c == class {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Encode writes the pages of doc as hOCR, like HOCRText returns them, so ParseHOCR reads doc again.
+func (doc *HOCRDocument) Encode(w io.Writer) error {
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", " ")
+	for _, page := range doc.Pages {
+		if err := encoder.EncodeElement(page, xml.StartElement{Name: xml.Name{Local: "div"}}); err != nil {
+			return err
+		}
+	}
+	return encoder.Flush()
+}
+
+// UnmarshalXML reads the text of the word, which may be wrapped by `<strong>` and `<em>`, and its chars.
+func (word *HOCRWord) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		if err := word.HOCRElement.setAttr(attr); err != nil {
+			return err
+		}
+	}
+	var text strings.Builder
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			switch {
+			case token.Name.Local == "strong":
+				word.Bold = true
+			case token.Name.Local == "em":
+				word.Italic = true
+			case hasClass(token, "ocrx_cinfo"):
+				char := &HOCRChar{}
+				if err := decoder.DecodeElement(char, &token); err != nil {
+					return err
+				}
+				word.Chars = append(word.Chars, char)
+				continue
+			default:
+				if err := decoder.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				word.Text = strings.TrimSpace(text.String())
+				if len(word.Chars) != 0 {
+					text.Reset()
+					for _, char := range word.Chars {
+						text.WriteString(char.Text)
+					}
+					word.Text = text.String()
+				}
+				return nil
+			}
+			depth--
+		case xml.CharData:
+			text.Write(token)
+		}
+	}
+}
+
+// MarshalXML writes the word like tesseract does.
+func (word *HOCRWord) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	start.Attr = word.HOCRElement.attrs()
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	var wrappers []string
+	if word.Bold {
+		wrappers = append(wrappers, "strong")
+	}
+	if word.Italic {
+		wrappers = append(wrappers, "em")
+	}
+	for _, wrapper := range wrappers {
+		if err := encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: wrapper}}); err != nil {
+			return err
+		}
+	}
+	if len(word.Chars) != 0 {
+		for _, char := range word.Chars {
+			if err := encoder.EncodeElement(char, xml.StartElement{Name: xml.Name{Local: "span"}}); err != nil {
+				return err
+			}
+		}
+	} else if err := encoder.EncodeToken(xml.CharData(word.Text)); err != nil {
+		return err
+	}
+	for i := len(wrappers) - 1; i >= 0; i-- {
+		if err := encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: wrappers[i]}}); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+func (element *HOCRElement) setAttr(attr xml.Attr) error {
+	switch attr.Name.Local {
+	case "class":
+		element.Class = attr.Value
+	case "id":
+		element.ID = attr.Value
+	case "lang":
+		element.Lang = attr.Value
+	case "dir":
+		element.Dir = attr.Value
+	case "title":
+		return element.Title.UnmarshalXMLAttr(attr)
+	}
+	return nil
+}
+
+func (element *HOCRElement) attrs() []xml.Attr {
+	attrs := []xml.Attr{{Name: xml.Name{Local: "class"}, Value: element.Class}}
+	for _, attr := range []xml.Attr{
+		{Name: xml.Name{Local: "id"}, Value: element.ID},
+		{Name: xml.Name{Local: "lang"}, Value: element.Lang},
+		{Name: xml.Name{Local: "dir"}, Value: element.Dir},
+		{Name: xml.Name{Local: "title"}, Value: element.Title.String()},
+	} {
+		if attr.Value != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+	return attrs
+}
+
+// HOCRTitle is the parsed title attribute of an hOCR element, which holds its properties,
+// e.g. `bbox 74 64 524 190; x_wconf 96`.
+type HOCRTitle []HOCRProperty
+
+// HOCRProperty is a property of a HOCRTitle. Values are kept as written, quoted strings
+// like the path of `image` keep their quotes.
+type HOCRProperty struct {
+	Name   string
+	Values []string
+}
+
+// ParseHOCRTitle parses the properties of title, which are separated by semicolons.
+// Semicolons and spaces within double quotes are part of the value.
+func ParseHOCRTitle(title string) (HOCRTitle, error) {
+	var properties HOCRTitle
+	var tokens []string
+	var token strings.Builder
+	quoted, inToken := false, false
+	endToken := func() {
+		if inToken {
+			tokens = append(tokens, token.String())
+			token.Reset()
+			inToken = false
+		}
+	}
+	endProperty := func() {
+		endToken()
+		if len(tokens) != 0 {
+			properties = append(properties, HOCRProperty{Name: tokens[0], Values: tokens[1:]})
+			tokens = nil
+		}
+	}
+	for _, r := range title {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			token.WriteRune(r)
+			inToken = true
+		case quoted:
+			token.WriteRune(r)
+		case r == ';':
+			endProperty()
+		case unicode.IsSpace(r):
+			endToken()
+		default:
+			token.WriteRune(r)
+			inToken = true
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in hOCR title %q", title)
+	}
+	endProperty()
+	return properties, nil
+}
+
+// String formats the properties like tesseract does.
+func (title HOCRTitle) String() string {
+	properties := make([]string, 0, len(title))
+	for _, property := range title {
+		properties = append(properties, strings.Join(append([]string{property.Name}, property.Values...), " "))
+	}
+	return strings.Join(properties, "; ")
+}
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr.
+func (title *HOCRTitle) UnmarshalXMLAttr(attr xml.Attr) error {
+	parsed, err := ParseHOCRTitle(attr.Value)
+	if err != nil {
+		return err
+	}
+	*title = parsed
+	return nil
+}
+
+// MarshalXMLAttr implements xml.MarshalerAttr.
+func (title HOCRTitle) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	if len(title) == 0 {
+		return xml.Attr{}, nil
+	}
+	return xml.Attr{Name: name, Value: title.String()}, nil
+}
+
+// Get returns the values of the property name.
+func (title HOCRTitle) Get(name string) ([]string, bool) {
+	for _, property := range title {
+		if property.Name == name {
+			return property.Values, true
+		}
+	}
+	return nil, false
+}
+
+// floats returns the values of the property name, if it has n numbers.
+func (title HOCRTitle) floats(name string, n int) ([]float64, bool) {
+	values, ok := title.Get(name)
+	if !ok || len(values) != n {
+		return nil, false
+	}
+	floats := make([]float64, n)
+	for i, value := range values {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, false
+		}
+		floats[i] = f
+	}
+	return floats, true
+}
+
+// BBox returns the `bbox` property, the bounding box of the element.
+func (title HOCRTitle) BBox() (image.Rectangle, bool) {
+	return title.rectangle("bbox")
+}
+
+// XBBoxes returns the `x_bboxes` property of `ocrx_cinfo`, the bounding box of the char.
+func (title HOCRTitle) XBBoxes() (image.Rectangle, bool) {
+	return title.rectangle("x_bboxes")
+}
+
+func (title HOCRTitle) rectangle(name string) (image.Rectangle, bool) {
+	values, ok := title.floats(name, 4)
+	if !ok {
+		return image.Rectangle{}, false
+	}
+	return image.Rect(int(values[0]), int(values[1]), int(values[2]), int(values[3])), true
+}
+
+// Baseline returns the `baseline` property of lines: the slope of the baseline, and its
+// offset from the bottom left corner of the bounding box, which is usually negative.
+func (title HOCRTitle) Baseline() (slope, offset float64, ok bool) {
+	values, ok := title.floats("baseline", 2)
+	if !ok {
+		return 0, 0, false
+	}
+	return values[0], values[1], true
+}
+
+// XWConf returns the `x_wconf` property of words, the confidence between 0 and 100.
+func (title HOCRTitle) XWConf() (float64, bool) {
+	return title.float("x_wconf")
+}
+
+// XConf returns the `x_conf` property of `ocrx_cinfo`, the confidence of the char between 0 and 100.
+func (title HOCRTitle) XConf() (float64, bool) {
+	return title.float("x_conf")
+}
+
+// XSize returns the `x_size` property of lines, the height of the line in pixels.
+func (title HOCRTitle) XSize() (float64, bool) {
+	return title.float("x_size")
+}
+
+// PPageNo returns the `ppageno` property of pages, the physical page number starting at 0.
+func (title HOCRTitle) PPageNo() (int, bool) {
+	value, ok := title.float("ppageno")
+	return int(value), ok
+}
+
+// Image returns the `image` property of pages, the path of the image without quotes.
+func (title HOCRTitle) Image() (string, bool) {
+	values, ok := title.Get("image")
+	if !ok || len(values) != 1 {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(values[0], `"`), `"`), true
+}
+
+func (title HOCRTitle) float(name string) (float64, bool) {
+	values, ok := title.floats(name, 1)
+	if !ok {
+		return 0, false
+	}
+	return values[0], true
+}
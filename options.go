@@ -0,0 +1,266 @@
+package gosseract
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// Option configures a Client constructed by New.
+type Option func(*clientConfig) error
+
+// clientConfig collects the options given to New, before the client is constructed.
+type clientConfig struct {
+	engine         *Engine
+	options        ClientOptions
+	languages      []string
+	pageSegMode    *PageSegMode
+	variables      map[SettableVariable]string
+	tessdataPrefix string
+	configFilePath string
+	ocrEngineMode  OcrEngineMode
+	trim           bool
+	// sourceResolution and resolutionClamp are like trim not part of the pool key.
+	sourceResolution int
+	resolutionClamp  bool
+}
+
+// New construct new Client configured by opts. Unlike the setters of Client, the configuration
+// is checked once up front: TessBaseAPI is initialized before New returns, so unknown languages,
+// variables or a broken config file are reported here instead of by the first call to Text.
+// It's due to caller to Close this client.
+func New(opts ...Option) (*Client, error) {
+	config, err := newClientConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return config.newClient()
+}
+
+func newClientConfig(opts ...Option) (*clientConfig, error) {
+	config := &clientConfig{
+		languages:       []string{"eng"},
+		variables:       map[SettableVariable]string{},
+		ocrEngineMode:   OEM_DEFAULT,
+		trim:            true,
+		resolutionClamp: true,
+	}
+	for _, opt := range opts {
+		if err := opt(config); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func (config *clientConfig) newClient() (*Client, error) {
+	engine := config.engine
+	if engine == nil {
+		var err error
+		if engine, err = getDefaultEngine(); err != nil {
+			return nil, err
+		}
+	}
+	wasm, err := engine.newApi(config.options)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newClient(wasm)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.apply(client); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// apply configures client and initializes its TessBaseAPI.
+func (config *clientConfig) apply(client *Client) error {
+	if err := config.applyUnkeyed(client); err != nil {
+		return err
+	}
+	client.Languages = append([]string(nil), config.languages...)
+	if err := client.SetOcrEngineMode(config.ocrEngineMode); err != nil {
+		return err
+	}
+	client.Variables = map[SettableVariable]string{}
+	for key, value := range config.variables {
+		client.Variables[key] = value
+	}
+	client.TessdataPrefix = ""
+	client.ConfigFilePath = ""
+	if config.tessdataPrefix != "" {
+		if err := client.SetTessdataPrefix(config.tessdataPrefix); err != nil {
+			return err
+		}
+	}
+	if config.configFilePath != "" {
+		if err := client.SetConfigFile(config.configFilePath); err != nil {
+			return err
+		}
+	}
+	if err := client.initAPI(); err != nil {
+		return err
+	}
+	if config.pageSegMode != nil {
+		return client.SetPageSegMode(*config.pageSegMode)
+	}
+	return nil
+}
+
+// applyUnkeyed configures the settings of client which are not part of the pool key, see Pool.Acquire.
+func (config *clientConfig) applyUnkeyed(client *Client) error {
+	client.Trim = config.trim
+	if err := client.SetSourceResolution(config.sourceResolution); err != nil {
+		return err
+	}
+	return client.SetResolutionClamp(config.resolutionClamp)
+}
+
+// WithLanguages sets languages to use. English as default.
+func WithLanguages(langs ...string) Option {
+	return func(config *clientConfig) error {
+		if len(langs) == 0 {
+			return fmt.Errorf("languages cannot be empty")
+		}
+		for _, lang := range langs {
+			if lang == "" {
+				return fmt.Errorf("language cannot be empty")
+			}
+		}
+		config.languages = langs
+		return nil
+	}
+}
+
+// WithPageSegMode sets "Page Segmentation Mode" (PSM), see Client.SetPageSegMode.
+func WithPageSegMode(mode PageSegMode) Option {
+	return func(config *clientConfig) error {
+		if mode < 0 || mode >= PSM_COUNT {
+			return fmt.Errorf("invalid page segmentation mode %d", mode)
+		}
+		config.pageSegMode = &mode
+		return nil
+	}
+}
+
+// WithVariables sets parameters, see Client.SetVariable.
+// Unknown keys are reported by New.
+func WithVariables(variables map[SettableVariable]string) Option {
+	return func(config *clientConfig) error {
+		for key, value := range variables {
+			if key == "" {
+				return fmt.Errorf("variable key cannot be empty")
+			}
+			config.variables[key] = value
+		}
+		return nil
+	}
+}
+
+// WithEngine creates the client from engine instead of the default engine.
+func WithEngine(engine *Engine) Option {
+	return func(config *clientConfig) error {
+		if engine == nil {
+			return fmt.Errorf("engine cannot be nil")
+		}
+		config.engine = engine
+		return nil
+	}
+}
+
+// WithFS mounts fsys at '/custom/', see ClientOptions.FS.
+func WithFS(fsys fs.FS) Option {
+	return func(config *clientConfig) error {
+		if fsys == nil {
+			return fmt.Errorf("fs cannot be nil")
+		}
+		config.options.FS = fsys
+		return nil
+	}
+}
+
+// WithHostFS selects the exposure of the host filesystem, see ClientOptions.HostFS.
+// allowedDirs are only used with HostFSReadOnlyDirs.
+func WithHostFS(mode HostFSMode, allowedDirs ...string) Option {
+	return func(config *clientConfig) error {
+		if mode == HostFSReadOnlyDirs && len(allowedDirs) == 0 {
+			return fmt.Errorf("allowed dirs cannot be empty with HostFSReadOnlyDirs")
+		}
+		config.options.HostFS = mode
+		config.options.AllowedDirs = allowedDirs
+		return nil
+	}
+}
+
+// WithTessdataPrefix sets path to the models directory, see Client.SetTessdataPrefix.
+func WithTessdataPrefix(prefix string) Option {
+	return func(config *clientConfig) error {
+		if prefix == "" {
+			return fmt.Errorf("tessdata prefix could not be empty")
+		}
+		config.tessdataPrefix = prefix
+		return nil
+	}
+}
+
+// WithConfigFile sets the file path to config file, see Client.SetConfigFile.
+func WithConfigFile(fpath string) Option {
+	return func(config *clientConfig) error {
+		if fpath == "" {
+			return fmt.Errorf("config file path could not be empty")
+		}
+		config.configFilePath = fpath
+		return nil
+	}
+}
+
+// WithOCREngineMode sets the OCR engine mode, see Client.SetOcrEngineMode.
+func WithOCREngineMode(mode OcrEngineMode) Option {
+	return func(config *clientConfig) error {
+		if mode < 0 || mode >= OEM_COUNT {
+			return fmt.Errorf("invalid OCR engine mode %d", mode)
+		}
+		config.ocrEngineMode = mode
+		return nil
+	}
+}
+
+// WithMemoryLimit limits the memory of the wasm module to limit bytes, see ClientOptions.MemoryLimit.
+func WithMemoryLimit(limit uint64) Option {
+	return func(config *clientConfig) error {
+		if err := checkMemoryLimit(limit); err != nil {
+			return err
+		}
+		config.options.MemoryLimit = limit
+		return nil
+	}
+}
+
+// WithSourceResolution sets the DPI of the images, see Client.SetSourceResolution.
+func WithSourceResolution(dpi int) Option {
+	return func(config *clientConfig) error {
+		if dpi <= 0 {
+			return fmt.Errorf("invalid source resolution %d", dpi)
+		}
+		config.sourceResolution = dpi
+		return nil
+	}
+}
+
+// WithResolutionClamp specifies whether low DPI of images are raised to 70, see Client.SetResolutionClamp.
+func WithResolutionClamp(clamp bool) Option {
+	return func(config *clientConfig) error {
+		config.resolutionClamp = clamp
+		return nil
+	}
+}
+
+// WithTrim specifies whether Text trims newlines from the result, true as default.
+func WithTrim(trim bool) Option {
+	return func(config *clientConfig) error {
+		config.trim = trim
+		return nil
+	}
+}
@@ -0,0 +1,472 @@
+package gosseract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// defaultPDFResolution is the DPI of pages, whose hOCR has no scan_res, like tesseract assumes.
+const defaultPDFResolution = 300
+
+// Numbers of the objects every PDF written by PDFWriter has, the objects of the pages follow.
+const (
+	pdfCatalog = iota + 1
+	pdfPages
+	pdfFont
+	pdfCIDFont
+	pdfFontDescriptor
+	pdfToUnicode
+	pdfCIDToGIDMap
+	pdfFontFile
+	pdfInfo
+	pdfFixedObjects = pdfInfo
+)
+
+// PDFOptions configures the PDF written by WritePDF and PDFWriter.
+type PDFOptions struct {
+	// Title is the title in the document information of the PDF.
+	Title string
+	// TextOnly omits the image, so only the invisible text layer is written, e.g. to merge
+	// it with the original scan by another tool. It's the option textonly_pdf of tesseract.
+	TextOnly bool
+}
+
+// WritePDF recognizes the image and writes it as a searchable PDF of one page to w:
+// the image with an invisible layer of the recognized text on top. The size of the page
+// is the size of the image, or of the rectangle given by SetRectangle, at its source resolution,
+// see SetSourceResolution.
+// The PDF renderer of tesseract is not part of the wasm build, so the PDF is written
+// from the result of HOCRText. Use PDFWriter for documents of many pages.
+func (client *Client) WritePDF(w io.Writer, opts PDFOptions) error {
+	return client.WritePDFContext(context.Background(), w, opts)
+}
+
+// WritePDFContext is WritePDF with a context.Context, see TextContext for the cancellation behaviour.
+func (client *Client) WritePDFContext(ctx context.Context, w io.Writer, opts PDFOptions) error {
+	pdf := NewPDFWriter(w, opts)
+	if err := pdf.AddPageContext(ctx, client); err != nil {
+		return err
+	}
+	return pdf.Close()
+}
+
+// PDFWriter writes a searchable PDF with a page for every image, see WritePDF.
+// The pages are written to w as they are added, Close completes the document.
+//
+//	pdf := gosseract.NewPDFWriter(w, gosseract.PDFOptions{})
+//	for _, path := range paths {
+//		client.SetImage(path)
+//		if err := pdf.AddPage(client); err != nil {
+//			return err
+//		}
+//	}
+//	return pdf.Close()
+type PDFWriter struct {
+	w    *countingWriter
+	opts PDFOptions
+	// offsets are the offsets of the objects in w, the one of object n is at n-1.
+	offsets []int64
+	// pages are the object numbers of the pages.
+	pages    []int
+	producer string
+	err      error
+	closed   bool
+}
+
+// NewPDFWriter constructs a PDFWriter writing to w.
+func NewPDFWriter(w io.Writer, opts PDFOptions) *PDFWriter {
+	return &PDFWriter{
+		w:       &countingWriter{w: w},
+		opts:    opts,
+		offsets: make([]int64, pdfFixedObjects),
+	}
+}
+
+// AddPage recognizes the image of client and writes it as the next page.
+func (pdf *PDFWriter) AddPage(client *Client) error {
+	return pdf.AddPageContext(context.Background(), client)
+}
+
+// AddPageContext is AddPage with a context.Context, see TextContext for the cancellation behaviour.
+func (pdf *PDFWriter) AddPageContext(ctx context.Context, client *Client) error {
+	if pdf.closed {
+		return fmt.Errorf("PDFWriter is closed")
+	}
+	if pdf.err != nil {
+		return pdf.err
+	}
+	hocr, err := client.HOCRTextContext(ctx)
+	if err != nil {
+		return err
+	}
+	doc, err := ParseHOCR(strings.NewReader(hocr))
+	if err != nil {
+		return err
+	}
+	if len(doc.Pages) != 1 {
+		return fmt.Errorf("failed to write PDF: expected 1 page of hOCR, got %d", len(doc.Pages))
+	}
+	var raster *pixRaster
+	if !pdf.opts.TextOnly {
+		if raster, err = client.wasm.readPixRaster(client.pixImage); err != nil {
+			return err
+		}
+	}
+	if pdf.producer == "" {
+		pdf.producer = "Tesseract " + client.Version()
+	}
+	pdf.writePage(doc.Pages[0], raster)
+	return pdf.err
+}
+
+// Close writes the fonts, the page tree and the trailer. It doesn't close the underlying writer.
+func (pdf *PDFWriter) Close() error {
+	if pdf.closed {
+		return pdf.err
+	}
+	pdf.closed = true
+	if pdf.err != nil {
+		return pdf.err
+	}
+	if len(pdf.pages) == 0 {
+		// A page tree without pages is not a valid PDF.
+		pdf.err = fmt.Errorf("failed to write PDF: no page was added")
+		return pdf.err
+	}
+
+	pdf.writeObject(pdfCatalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPages))
+	kids := make([]string, len(pdf.pages))
+	for i, page := range pdf.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+	}
+	pdf.writeObject(pdfPages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pdf.pages)))
+
+	// The text is written in the glyphless font of the tesseract renderer: every code is a glyph
+	// of half the height wide, and the codes are the UTF-16 code units of the text.
+	// Like tesseract, the font program is embedded, and the CIDToGIDMap maps every code to its glyph.
+	pdf.writeObject(pdfFont, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /GlyphLessFont /Encoding /Identity-H"+
+		" /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", pdfCIDFont, pdfToUnicode))
+	pdf.writeObject(pdfCIDFont, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /GlyphLessFont"+
+		" /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >>"+
+		" /FontDescriptor %d 0 R /CIDToGIDMap %d 0 R /DW %d >>", pdfFontDescriptor, pdfCIDToGIDMap, glyphLessWidth))
+	pdf.writeObject(pdfFontDescriptor, fmt.Sprintf("<< /Type /FontDescriptor /FontName /GlyphLessFont /Flags 5"+
+		" /FontBBox [0 0 %d %d] /ItalicAngle 0 /Ascent %d /Descent 0 /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		glyphLessWidth, glyphLessUnitsPerEm, glyphLessUnitsPerEm, glyphLessUnitsPerEm, pdfFontFile))
+	pdf.writeStream(pdfToUnicode, "", []byte(glyphLessToUnicode))
+	pdf.writeStream(pdfCIDToGIDMap, "/Filter /FlateDecode", deflate(bytes.Repeat([]byte{0, 1}, 1<<16)))
+	pdf.writeStream(pdfFontFile, fmt.Sprintf("/Length1 %d /Filter /FlateDecode", len(glyphLessFont)), deflate(glyphLessFont))
+
+	info := "<< /Producer " + pdfString(pdf.producer)
+	if pdf.opts.Title != "" {
+		info += " /Title " + pdfString(pdf.opts.Title)
+	}
+	pdf.writeObject(pdfInfo, info+" >>")
+
+	xref := pdf.w.n
+	pdf.printf("xref\n0 %d\n0000000000 65535 f \n", len(pdf.offsets)+1)
+	for _, offset := range pdf.offsets {
+		pdf.printf("%010d 00000 n \n", offset)
+	}
+	pdf.printf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(pdf.offsets)+1, pdfCatalog, pdfInfo, xref)
+	return pdf.err
+}
+
+// glyphLessWidth is the width of every glyph of the glyphless font in thousandths of the font size.
+const glyphLessWidth = 500
+
+// glyphLessUnitsPerEm is the font size in the units of the glyphless font, so a unit is a thousandth.
+const glyphLessUnitsPerEm = 1000
+
+// glyphLessFont is the TrueType program of the glyphless font. It has two glyphs without outline,
+// .notdef and the one all codes are mapped to, and the tables PDF needs of an embedded font.
+var glyphLessFont = newGlyphLessFont()
+
+func newGlyphLessFont() []byte {
+	u16 := func(values ...int) []byte {
+		b := make([]byte, 0, 2*len(values))
+		for _, v := range values {
+			b = append(b, byte(v>>8), byte(v))
+		}
+		return b
+	}
+	u32 := func(values ...uint32) []byte {
+		b := make([]byte, 0, 4*len(values))
+		for _, v := range values {
+			b = append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+		}
+		return b
+	}
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+	// checksum sums the big endian words of b, padded with zeros.
+	checksum := func(b []byte) uint32 {
+		var sum uint32
+		for i := 0; i < len(b); i += 4 {
+			var word [4]byte
+			copy(word[:], b[i:])
+			sum += uint32(word[0])<<24 | uint32(word[1])<<16 | uint32(word[2])<<8 | uint32(word[3])
+		}
+		return sum
+	}
+
+	// The tables sorted by tag, as the table directory requires.
+	tables := []struct {
+		tag  string
+		data []byte
+	}{
+		// A Windows Unicode format 4 subtable with the final segment only, which maps no character:
+		// the codes of the text are glyph ids, not characters.
+		{"cmap", join(u16(0, 1, 3, 1), u32(12), u16(4, 24, 0, 2, 2, 0, 0, 0xffff, 0, 0xffff, 1, 0))},
+		{"glyf", nil},
+		{"head", join(u32(0x10000, 0x10000, 0, 0x5f0f3cf5), u16(3, glyphLessUnitsPerEm), u32(0, 0, 0, 0),
+			u16(0, 0, glyphLessWidth, glyphLessUnitsPerEm, 0, 8, 2, 0, 0))},
+		{"hhea", join(u32(0x10000), u16(glyphLessUnitsPerEm, 0, 0, glyphLessWidth, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2))},
+		{"hmtx", u16(glyphLessWidth, 0, glyphLessWidth, 0)},
+		{"loca", u16(0, 0, 0)},
+		{"maxp", join(u32(0x10000), u16(2, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0))},
+		{"post", join(u32(0x30000, 0), u16(0, 0), u32(1, 0, 0, 0, 0))},
+	}
+	// The search range of the 8 tables is 8*16, so the entry selector is 3 and the range shift 0.
+	font := join(u32(0x10000), u16(len(tables), 128, 3, 0))
+	offset := len(font) + 16*len(tables)
+	var data []byte
+	head := 0
+	for _, table := range tables {
+		if table.tag == "head" {
+			head = offset
+		}
+		font = append(font, table.tag...)
+		font = append(font, u32(checksum(table.data), uint32(offset), uint32(len(table.data)))...)
+		// Every table starts at a multiple of 4.
+		padded := append(table.data, make([]byte, -len(table.data)&3)...)
+		data = append(data, padded...)
+		offset += len(padded)
+	}
+	font = append(font, data...)
+	// checkSumAdjustment of head makes the checksum of the whole font 0xb1b0afba.
+	copy(font[head+8:], u32(0xb1b0afba-checksum(font)))
+	return font
+}
+
+// glyphLessToUnicode maps the codes of the glyphless font to the same UTF-16 code units.
+const glyphLessToUnicode = `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def
+/CMapName /Adobe-Identity-UCS def
+/CMapType 2 def
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+1 beginbfrange
+<0000> <FFFF> <0000>
+endbfrange
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end
+`
+
+// writePage writes the objects of page, and the part of raster within the page unless it's nil.
+func (pdf *PDFWriter) writePage(page *HOCRPage, raster *pixRaster) {
+	pdf.writeHeader()
+	box, _ := page.Title.BBox()
+	dpi := defaultPDFResolution
+	if values, ok := page.Title.Get("scan_res"); ok && len(values) != 0 {
+		if res, err := strconv.Atoi(values[0]); err == nil && res > 0 {
+			dpi = res
+		}
+	}
+	// scale converts pixels to points.
+	scale := 72 / float64(dpi)
+	width, height := float64(box.Dx())*scale, float64(box.Dy())*scale
+
+	var content bytes.Buffer
+	resources := fmt.Sprintf("/Font << /F0 %d 0 R >>", pdfFont)
+	// The page is the part of the image which was recognized, e.g. the rectangle given by SetRectangle.
+	if raster != nil {
+		if rect := box.Intersect(image.Rect(0, 0, raster.width, raster.height)); !rect.Empty() {
+			raster = raster.crop(rect)
+			imageObject := pdf.newObject()
+			pdf.writeImage(imageObject, raster)
+			resources += fmt.Sprintf(" /XObject << /Im0 %d 0 R >>", imageObject)
+			fmt.Fprintf(&content, "q %s 0 0 %s %s %s cm /Im0 Do Q\n",
+				pdfNumber(float64(rect.Dx())*scale), pdfNumber(float64(rect.Dy())*scale),
+				pdfNumber(float64(rect.Min.X-box.Min.X)*scale), pdfNumber(float64(box.Max.Y-rect.Max.Y)*scale))
+		}
+	}
+	writeTextLayer(&content, page, func(p image.Point) (float64, float64) {
+		return float64(p.X-box.Min.X) * scale, float64(box.Max.Y-p.Y) * scale
+	}, scale)
+
+	contents := pdf.newObject()
+	pdf.writeStream(contents, "/Filter /FlateDecode", deflate(content.Bytes()))
+	pageObject := pdf.newObject()
+	pdf.writeObject(pageObject, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << %s >> /Contents %d 0 R >>",
+		pdfPages, pdfNumber(width), pdfNumber(height), resources, contents))
+	pdf.pages = append(pdf.pages, pageObject)
+}
+
+// writeTextLayer writes the words of page as invisible text to content. Every word but the last
+// of a line is followed by a space, and stretched to the next word, so the text can be selected
+// and copied like the text of Text. point converts a point of the image to the page, scale pixels to points.
+func writeTextLayer(content io.Writer, page *HOCRPage, point func(image.Point) (float64, float64), scale float64) {
+	fmt.Fprintf(content, "BT\n3 Tr\n")
+	for _, area := range page.Areas {
+		for _, paragraph := range area.Paragraphs {
+			for _, line := range paragraph.Lines {
+				lineBox, _ := line.Title.BBox()
+				// The baseline is relative to the bottom left corner of the line, in pixels down.
+				slope, offset, _ := line.Title.Baseline()
+				baseline := func(x int) int {
+					return lineBox.Max.Y + int(math.Round(offset+slope*float64(x-lineBox.Min.X)))
+				}
+				words := make([]*HOCRWord, 0, len(line.Words))
+				for _, word := range line.Words {
+					if word.Text != "" {
+						words = append(words, word)
+					}
+				}
+				for i, word := range words {
+					wordBox, _ := word.Title.BBox()
+					text, end := word.Text, wordBox.Max.X
+					if i != len(words)-1 {
+						next, _ := words[i+1].Title.BBox()
+						if next.Min.X > end {
+							text, end = text+" ", next.Min.X
+						}
+					}
+					units := utf16.Encode([]rune(text))
+					// The font size is the height of the line above the baseline.
+					start := image.Pt(wordBox.Min.X, baseline(wordBox.Min.X))
+					size := float64(start.Y-lineBox.Min.Y) * scale
+					if size <= 0 {
+						size = float64(lineBox.Dy()) * scale
+					}
+					if size <= 0 || end <= wordBox.Min.X {
+						continue
+					}
+					angle := math.Atan(slope)
+					length := float64(end-wordBox.Min.X) * scale / math.Cos(angle)
+					stretch := 100 * length / (float64(len(units)) * size * glyphLessWidth / 1000)
+					x, y := point(start)
+					// The image is y down, so the angle of the baseline is negated on the page.
+					cos, sin := math.Cos(-angle), math.Sin(-angle)
+					fmt.Fprintf(content, "/F0 %s Tf\n%s %s %s %s %s %s Tm\n%s Tz\n<",
+						pdfNumber(size), pdfNumber(cos), pdfNumber(sin), pdfNumber(-sin), pdfNumber(cos),
+						pdfNumber(x), pdfNumber(y), pdfNumber(stretch))
+					for _, unit := range units {
+						fmt.Fprintf(content, "%04X", unit)
+					}
+					fmt.Fprintf(content, "> Tj\n")
+				}
+			}
+		}
+	}
+	fmt.Fprintf(content, "ET\n")
+}
+
+// writeImage writes raster as an image XObject.
+func (pdf *PDFWriter) writeImage(object int, raster *pixRaster) {
+	colorSpace, depth, decode := "/DeviceGray", raster.depth, ""
+	switch {
+	case raster.colormap != nil:
+		colorSpace = fmt.Sprintf("[/Indexed /DeviceRGB %d <%X>]", len(raster.colormap)/3-1, raster.colormap)
+	case raster.depth == 24:
+		colorSpace, depth = "/DeviceRGB", 8
+	case raster.depth == 1:
+		// Leptonica stores black as 1.
+		decode = " /Decode [1 0]"
+	}
+	pdf.writeStream(object, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent %d%s /Filter /FlateDecode",
+		raster.width, raster.height, colorSpace, depth, decode), deflate(raster.data))
+}
+
+// writeHeader writes the header of the file, before the first object.
+func (pdf *PDFWriter) writeHeader() {
+	if pdf.w.n == 0 {
+		// The comment of binary characters marks the file as binary for transfer programs.
+		pdf.printf("%%PDF-1.5\n%%\xe2\xe3\xcf\xd3\n")
+	}
+}
+
+// newObject allocates the number of an object of a page.
+func (pdf *PDFWriter) newObject() int {
+	pdf.offsets = append(pdf.offsets, 0)
+	return len(pdf.offsets)
+}
+
+func (pdf *PDFWriter) writeObject(object int, value string) {
+	pdf.offsets[object-1] = pdf.w.n
+	pdf.printf("%d 0 obj\n%s\nendobj\n", object, value)
+}
+
+// writeStream writes a stream object of data, whose dictionary has the entries dict besides the length.
+func (pdf *PDFWriter) writeStream(object int, dict string, data []byte) {
+	if dict != "" {
+		dict += " "
+	}
+	pdf.offsets[object-1] = pdf.w.n
+	pdf.printf("%d 0 obj\n<< %s/Length %d >>\nstream\n", object, dict, len(data))
+	if pdf.err == nil {
+		_, pdf.err = pdf.w.Write(data)
+	}
+	pdf.printf("\nendstream\nendobj\n")
+}
+
+func (pdf *PDFWriter) printf(format string, args ...interface{}) {
+	if pdf.err == nil {
+		_, pdf.err = fmt.Fprintf(pdf.w, format, args...)
+	}
+}
+
+// countingWriter counts the bytes written, which are the offsets of the objects.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+func deflate(data []byte) []byte {
+	var b bytes.Buffer
+	z := zlib.NewWriter(&b)
+	z.Write(data)
+	z.Close()
+	return b.Bytes()
+}
+
+// pdfNumber formats f with at most 3 decimals.
+func pdfNumber(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 3, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// pdfString formats s as a text string, which is UTF-16 with a byte order mark.
+func pdfString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	b.WriteString(">")
+	return b.String()
+}
@@ -0,0 +1,219 @@
+package gosseract
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// pixInfo is a struct pix_info of tessbridge.h, which describes a Pix.
+type pixInfo struct {
+	width, height, depth, wpl int
+	// data is the address of the raster in the module memory.
+	data uint32
+	// colormap holds the RGB triples of the colors of a colormapped Pix, nil otherwise.
+	colormap []byte
+}
+
+// getPixInfo copies the description of the Pix at pixPtr out of the module memory.
+func (t *tesseractApi) getPixInfo(pixPtr uint64) (*pixInfo, error) {
+	res, err := t.GetPixInfo(pixPtr)
+	if err != nil {
+		return nil, err
+	}
+	defer t.free(res[0])
+	mem := t.module.Memory()
+	// pixInfoSize is sizeof(struct pix_info) in wasm32.
+	const pixInfoSize = 7 * 4
+	if _, ok := mem.Read(uint32(res[0]), pixInfoSize); !ok {
+		return nil, &WasmError{Func: "GetPixInfo", Kind: ErrWasmTrap, Err: fmt.Errorf("pointer %d is out of range", res[0])}
+	}
+	field := func(i int) uint32 {
+		x, _ := mem.ReadUint32Le(uint32(res[0]) + uint32(4*i))
+		return x
+	}
+	info := &pixInfo{
+		width:  int(field(0)),
+		height: int(field(1)),
+		depth:  int(field(2)),
+		wpl:    int(field(3)),
+		data:   field(4),
+	}
+	if colormapPtr := field(6); colormapPtr != 0 {
+		defer t.free(uint64(colormapPtr))
+		colormap, ok := mem.Read(colormapPtr, 3*field(5))
+		if !ok {
+			return nil, &WasmError{Func: "GetPixInfo", Kind: ErrWasmTrap, Err: fmt.Errorf("colormap of pix %d is out of range", pixPtr)}
+		}
+		info.colormap = append([]byte(nil), colormap...)
+	}
+	return info, nil
+}
+
+// createPixImage builds a leptonica Pix with the pixels of img in the module memory,
+// without encoding and decoding it. Gray images become 8 bpp, all others 32 bpp RGB.
+// The Pix is created by leptonica, only its raster is written here, so the result
+// can be released by DestroyPixImage.
+func (t *tesseractApi) createPixImage(img image.Image) (uint64, error) {
+	bounds := img.Bounds()
+	depth := 32
+	if isGray(img) {
+		depth = 8
+	}
+	res, err := t.CreatePix(uint64(bounds.Dx()), uint64(bounds.Dy()), uint64(depth))
+	if err != nil {
+		return 0, err
+	}
+	if res[0] == 0 {
+		return 0, &WasmError{Func: "CreatePix", Kind: ErrOutOfMemory, Err: fmt.Errorf("could not create a pix of %dx%d", bounds.Dx(), bounds.Dy())}
+	}
+	pixPtr := res[0]
+	info, err := t.getPixInfo(pixPtr)
+	if err != nil {
+		t.DestroyPixImage(pixPtr)
+		return 0, err
+	}
+	if !t.module.Memory().Write(info.data, pixImageData(img, depth, info.wpl)) {
+		t.DestroyPixImage(pixPtr)
+		return 0, &WasmError{Func: "CreatePix", Kind: ErrWasmTrap, Err: fmt.Errorf("data of pix %d is out of range", pixPtr)}
+	}
+	return pixPtr, nil
+}
+
+// pixImageData lays out the pixels of img like leptonica does: lines of wpl 32 bit words,
+// which are stored little endian in the module memory. 8 bpp pixels are packed into a
+// word with the leftmost pixel as most significant byte, 32 bpp pixels are 0xRRGGBBAA words.
+func pixImageData(img image.Image, depth, wpl int) []byte {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	data := make([]byte, 4*wpl*height)
+
+	for y := 0; y < height; y++ {
+		line := data[4*wpl*y:]
+		if depth == 8 {
+			if gray, ok := img.(*image.Gray); ok {
+				row := gray.Pix[gray.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+				for x := 0; x < width; x++ {
+					line[x^3] = row[x]
+				}
+				continue
+			}
+			for x := 0; x < width; x++ {
+				line[x^3] = color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray).Y
+			}
+			continue
+		}
+		for x := 0; x < width; x++ {
+			var r, g, b, a uint32
+			switch img := img.(type) {
+			case *image.RGBA:
+				i := img.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+				r, g, b, a = uint32(img.Pix[i])*0x101, uint32(img.Pix[i+1])*0x101, uint32(img.Pix[i+2])*0x101, uint32(img.Pix[i+3])*0x101
+			default:
+				r, g, b, a = img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			}
+			// Composite onto white to drop the alpha channel, as tesseract ignores it.
+			white := 0xffff - a
+			line[4*x] = 0xff
+			line[4*x+1] = byte((b + white) >> 8)
+			line[4*x+2] = byte((g + white) >> 8)
+			line[4*x+3] = byte((r + white) >> 8)
+		}
+	}
+	return data
+}
+
+// isGray reports whether img has gray pixels only, so it fits into an 8 bpp Pix.
+func isGray(img image.Image) bool {
+	switch img := img.(type) {
+	case *image.Gray:
+		return true
+	case *image.Paletted:
+		for _, c := range img.Palette {
+			r, g, b, a := c.RGBA()
+			if r != g || g != b || a != 0xffff {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// pixRaster is the raster of a Pix with its lines packed into bytes, most significant bit first,
+// the layout of image XObjects in PDF.
+type pixRaster struct {
+	width, height int
+	// depth is the bits per pixel of data: 1, 2, 4, 8 or 16 for gray or colormapped pixels,
+	// and 24 for RGB pixels, which leptonica stores as 32 bpp.
+	depth int
+	// colormap holds the RGB triples of the colors of a colormapped Pix, nil otherwise.
+	colormap []byte
+	data     []byte
+}
+
+// readPixRaster copies the raster of the Pix at pixPtr out of the module memory.
+func (t *tesseractApi) readPixRaster(pixPtr uint64) (*pixRaster, error) {
+	info, err := t.getPixInfo(pixPtr)
+	if err != nil {
+		return nil, err
+	}
+	width, height, depth, wpl := info.width, info.height, info.depth, info.wpl
+	words, ok := t.module.Memory().Read(info.data, uint32(4*wpl*height))
+	if !ok {
+		return nil, &WasmError{Func: "readPixRaster", Kind: ErrWasmTrap, Err: fmt.Errorf("data of pix %d is out of range", pixPtr)}
+	}
+
+	raster := &pixRaster{width: width, height: height, depth: depth}
+	if depth == 32 {
+		raster.depth = 24
+	}
+	stride := (width*raster.depth + 7) / 8
+	raster.data = make([]byte, stride*height)
+	for y := 0; y < height; y++ {
+		line := words[4*wpl*y : 4*wpl*(y+1)]
+		row := raster.data[stride*y : stride*(y+1)]
+		if depth == 32 {
+			// 0xRRGGBBAA words, little endian in memory.
+			for x := 0; x < width; x++ {
+				row[3*x], row[3*x+1], row[3*x+2] = line[4*x+3], line[4*x+2], line[4*x+1]
+			}
+			continue
+		}
+		for i := range row {
+			row[i] = line[i^3]
+		}
+	}
+
+	raster.colormap = info.colormap
+	return raster, nil
+}
+
+// crop returns the part of raster within rect, which must be within the raster.
+func (raster *pixRaster) crop(rect image.Rectangle) *pixRaster {
+	if rect == image.Rect(0, 0, raster.width, raster.height) {
+		return raster
+	}
+	cropped := &pixRaster{width: rect.Dx(), height: rect.Dy(), depth: raster.depth, colormap: raster.colormap}
+	stride := (raster.width*raster.depth + 7) / 8
+	croppedStride := (cropped.width*cropped.depth + 7) / 8
+	cropped.data = make([]byte, croppedStride*cropped.height)
+	for y := 0; y < cropped.height; y++ {
+		line := raster.data[stride*(rect.Min.Y+y) : stride*(rect.Min.Y+y+1)]
+		row := cropped.data[croppedStride*y : croppedStride*(y+1)]
+		if raster.depth%8 == 0 {
+			copy(row, line[rect.Min.X*raster.depth/8:])
+			continue
+		}
+		// Pixels of less than a byte are shifted bit by bit.
+		for x := 0; x < cropped.width; x++ {
+			for bit := 0; bit < raster.depth; bit++ {
+				from, to := (rect.Min.X+x)*raster.depth+bit, x*raster.depth+bit
+				if line[from/8]&(0x80>>(from%8)) != 0 {
+					row[to/8] |= 0x80 >> (to % 8)
+				}
+			}
+		}
+	}
+	return cropped
+}
@@ -0,0 +1,297 @@
+package gosseract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrPoolClosed is reported by Pool.Acquire after the pool was closed.
+var ErrPoolClosed = errors.New("pool is closed")
+
+// Pool keeps initialized clients for reuse, because constructing a Client is expensive.
+// A Client is not safe for concurrent use, but a Pool is: each goroutine acquires a
+// client of its own and releases it afterwards.
+//
+// Clients are keyed by their recognition settings, i.e. languages, config file,
+// tessdata prefix, page segmentation mode, OCR engine mode and variables.
+// At most size clients are alive in total, whatever their keys, as each of them
+// holds a wasm instance with a memory of its own.
+type Pool struct {
+	size int
+	// opts are applied to every client before the options given to Acquire.
+	opts []Option
+
+	mu     sync.Mutex
+	closed bool
+	// alive is the number of clients created and not closed yet, including the ones being created.
+	alive int
+	// idle are the clients ready to be acquired, the least recently released first.
+	idle    []*Client
+	clients map[*Client]*pooledClient
+	// changed is closed and replaced whenever a client becomes idle or is closed, to wake up Acquire.
+	changed chan struct{}
+}
+
+// pooledClient remembers how a client was configured, to reset it on release.
+type pooledClient struct {
+	key         string
+	config      *clientConfig
+	pageSegMode PageSegMode
+	// tessdataPrefix and configFilePath are the paths as client stored them, which may differ from config.
+	tessdataPrefix string
+	configFilePath string
+}
+
+// NewPool creates a pool keeping up to size clients.
+// opts are applied to every client, which is the place for WithEngine, WithFS, WithHostFS and WithMemoryLimit:
+// these are not part of the key, so they should not be given to Acquire.
+// The memory of the pool is bounded by size times the memory of a client, which is up to the
+// 1GB the wasm module is built with, or the limit given by WithMemoryLimit.
+func NewPool(size int, opts ...Option) (*Pool, error) {
+	if size < 1 {
+		return nil, fmt.Errorf("pool size must be at least 1")
+	}
+	if _, err := newClientConfig(opts...); err != nil {
+		return nil, err
+	}
+	return &Pool{
+		size:    size,
+		opts:    opts,
+		clients: map[*Client]*pooledClient{},
+		changed: make(chan struct{}),
+	}, nil
+}
+
+// Acquire returns an initialized client configured by the options of the pool and opts.
+// An idle client with the same recognition settings is reused. Otherwise a new client is created,
+// and if the pool is full, the least recently released idle client is closed to make room for it.
+// If all clients are in use, it waits until one is released or ctx is done.
+// The client must be given back by Release, not by Close.
+func (pool *Pool) Acquire(ctx context.Context, opts ...Option) (*Client, error) {
+	config, err := newClientConfig(append(append([]Option(nil), pool.opts...), opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	key := config.key()
+
+	for {
+		pool.mu.Lock()
+		if pool.closed {
+			pool.mu.Unlock()
+			return nil, ErrPoolClosed
+		}
+		if client := pool.takeIdle(key); client != nil {
+			pooled := pool.clients[client]
+			pooled.config = config
+			pool.mu.Unlock()
+			// Trim and the resolution are not part of the key, so they are set on every Acquire.
+			if err := config.applyUnkeyed(client); err != nil {
+				pool.Release(client)
+				return nil, err
+			}
+			return client, nil
+		}
+		if pool.alive < pool.size {
+			pool.alive++
+			pool.mu.Unlock()
+			return pool.create(config, key)
+		}
+		if len(pool.idle) != 0 {
+			// The evicted client leaves its place to the new one, so alive stays the same.
+			evicted := pool.idle[0]
+			pool.idle = pool.idle[1:]
+			delete(pool.clients, evicted)
+			pool.mu.Unlock()
+			evicted.Close()
+			return pool.create(config, key)
+		}
+		changed := pool.changed
+		pool.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// create constructs a client for a place the caller counted in alive already.
+func (pool *Pool) create(config *clientConfig, key string) (*Client, error) {
+	client, err := config.newClient()
+	if err != nil {
+		pool.free()
+		return nil, err
+	}
+	res, err := client.wasm.GetPageSegMode(client.api)
+	if err != nil {
+		client.Close()
+		pool.free()
+		return nil, err
+	}
+	pool.mu.Lock()
+	pool.clients[client] = &pooledClient{
+		key:            key,
+		config:         config,
+		pageSegMode:    PageSegMode(res[0]),
+		tessdataPrefix: client.TessdataPrefix,
+		configFilePath: client.ConfigFilePath,
+	}
+	pool.mu.Unlock()
+	return client, nil
+}
+
+// Release gives client back to the pool. The image is destroyed and the settings changed
+// since Acquire are reset. Clients which trapped are closed, to be replaced by a new one.
+// Clients not acquired from this pool are ignored.
+func (pool *Pool) Release(client *Client) {
+	pool.mu.Lock()
+	pooled, ok := pool.clients[client]
+	closed := pool.closed
+	pool.mu.Unlock()
+	if !ok {
+		return
+	}
+	if closed || pooled.reset(client) != nil {
+		pool.discard(client)
+		return
+	}
+	// The client is put back while holding the lock, so Close either sees it idle or
+	// has already marked the pool closed.
+	pool.mu.Lock()
+	if !pool.closed {
+		pool.idle = append(pool.idle, client)
+		pool.notify()
+		pool.mu.Unlock()
+		return
+	}
+	pool.mu.Unlock()
+	pool.discard(client)
+}
+
+// Close closes the idle clients. Clients in use are closed when they are released.
+func (pool *Pool) Close() error {
+	pool.mu.Lock()
+	pool.closed = true
+	idle := pool.idle
+	pool.idle = nil
+	for _, client := range idle {
+		delete(pool.clients, client)
+	}
+	pool.alive -= len(idle)
+	pool.notify()
+	pool.mu.Unlock()
+
+	var err error
+	for _, client := range idle {
+		if cerr := client.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// takeIdle removes the most recently released idle client with key from idle, if any.
+// It must be called with mu held.
+func (pool *Pool) takeIdle(key string) *Client {
+	for i := len(pool.idle) - 1; i >= 0; i-- {
+		client := pool.idle[i]
+		if pool.clients[client].key == key {
+			pool.idle = append(pool.idle[:i], pool.idle[i+1:]...)
+			return client
+		}
+	}
+	return nil
+}
+
+// notify wakes up the waiting Acquire calls. It must be called with mu held.
+func (pool *Pool) notify() {
+	close(pool.changed)
+	pool.changed = make(chan struct{})
+}
+
+// free gives up a place counted in alive.
+func (pool *Pool) free() {
+	pool.mu.Lock()
+	pool.alive--
+	pool.notify()
+	pool.mu.Unlock()
+}
+
+func (pool *Pool) discard(client *Client) {
+	pool.mu.Lock()
+	delete(pool.clients, client)
+	pool.mu.Unlock()
+	client.Close()
+	pool.free()
+}
+
+// reset brings client back to the state right after Acquire.
+func (pooled *pooledClient) reset(client *Client) error {
+	if client.wasm == nil {
+		return ErrClientClosed
+	}
+	if client.wasm.err != nil {
+		return client.wasm.err
+	}
+	if err := client.destroyPixImage(); err != nil {
+		return err
+	}
+	if _, err := client.wasm.Clear(client.api); err != nil {
+		return err
+	}
+	if pooled.isDirty(client) {
+		// TessBaseAPI keeps variables until it is ended, so it is constructed again.
+		if _, err := client.wasm.Free(client.api); err != nil {
+			return err
+		}
+		res, err := client.wasm.Create()
+		if err != nil {
+			return err
+		}
+		client.api = res[0]
+		client.flagForInit()
+		if err := pooled.config.apply(client); err != nil {
+			return err
+		}
+	}
+	if err := pooled.config.applyUnkeyed(client); err != nil {
+		return err
+	}
+	client.ClearRectangle()
+	return client.SetPageSegMode(pooled.pageSegMode)
+}
+
+// isDirty reports whether the settings of client were changed since Acquire.
+func (pooled *pooledClient) isDirty(client *Client) bool {
+	config := pooled.config
+	return client.shouldInit ||
+		!reflect.DeepEqual(client.Languages, config.languages) ||
+		!reflect.DeepEqual(client.Variables, config.variables) ||
+		client.ocrEngineMode != config.ocrEngineMode ||
+		client.TessdataPrefix != pooled.tessdataPrefix ||
+		client.ConfigFilePath != pooled.configFilePath
+}
+
+// key identifies the recognition settings of config. The filesystem and memory options are not part of it.
+func (config *clientConfig) key() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%q %q %q %d", config.languages, config.tessdataPrefix, config.configFilePath, config.ocrEngineMode)
+	if config.pageSegMode != nil {
+		fmt.Fprintf(&b, " psm=%d", *config.pageSegMode)
+	}
+	keys := make([]string, 0, len(config.variables))
+	for key := range config.variables {
+		keys = append(keys, string(key))
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, " %q=%q", key, config.variables[SettableVariable(key)])
+	}
+	return b.String()
+}
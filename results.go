@@ -0,0 +1,404 @@
+package gosseract
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// resultSize is sizeof(struct result) in wasm32, see tessbridge.h.
+const resultSize = 100
+
+// Offsets of the fields of struct result.
+const (
+	resultLevel          = 0
+	resultBox            = 4
+	resultText           = 20
+	resultConfidence     = 24
+	resultLanguage       = 28
+	resultFromDictionary = 32
+	resultNumeric        = 33
+	resultSuperscript    = 34
+	resultSubscript      = 35
+	resultDropcap        = 36
+	resultBold           = 37
+	resultItalic         = 38
+	resultUnderlined     = 39
+	resultMonospace      = 40
+	resultSerif          = 41
+	resultSmallCaps      = 42
+	resultPointSize      = 44
+	resultFontName       = 48
+	resultBaseline       = 52
+	resultOrientation    = 68
+	resultWritingDir     = 72
+	resultTextlineOrder  = 76
+	resultDeskewAngle    = 80
+	resultJustification  = 84
+	resultFirstIndent    = 88
+	resultHasBaseline    = 92
+	resultListItem       = 93
+	resultCrown          = 94
+	resultBlockType      = 96
+)
+
+// Result holds what the elements of every level of the page have in common.
+type Result struct {
+	// Box is the bounding box in coordinates of the whole image.
+	Box image.Rectangle
+	// Text is the UTF8 text of the element, including the newlines of lines and paragraphs.
+	Text string
+	// Confidence is the mean confidence of the element, between 0 and 100.
+	Confidence float64
+}
+
+// ResultPage is the tree of the results of one recognition, see Client.Recognize.
+type ResultPage struct {
+	Blocks []*ResultBlock
+}
+
+// ResultBlock is a block of text, or of an image or a separator line without children.
+type ResultBlock struct {
+	Result
+	BlockType  PolyBlockType
+	Paragraphs []*ResultParagraph
+}
+
+// ResultParagraph is a paragraph within a block.
+type ResultParagraph struct {
+	Result
+	ParagraphInfo
+	TextOrientation
+	Lines []*ResultLine
+}
+
+// ParagraphInfo describes the layout of a paragraph.
+type ParagraphInfo struct {
+	Justification ParagraphJustification
+	// IsListItem is set for paragraphs which are items of a list.
+	IsListItem bool
+	// IsCrown is set for the first paragraph after a heading, which is not indented
+	// like the other paragraphs.
+	IsCrown bool
+	// FirstLineIndent is the indentation of the first line in pixels, relative to the other lines.
+	FirstLineIndent int
+}
+
+// ResultLine is a line within a paragraph.
+type ResultLine struct {
+	Result
+	// Baseline is the line the text sits on, or nil if it's unknown.
+	Baseline *Baseline
+	TextOrientation
+	Words []*ResultWord
+}
+
+// Baseline is the line the text of a line sits on, in coordinates of the whole image.
+type Baseline struct {
+	Start, End image.Point
+}
+
+// TextOrientation describes the direction of the text of the block of a paragraph or line.
+type TextOrientation struct {
+	Orientation      Orientation
+	WritingDirection WritingDirection
+	TextlineOrder    TextlineOrder
+	// DeskewAngle is the angle in radians, by which the block is rotated counterclockwise
+	// to make its lines horizontal, after rotating it upright by Orientation.
+	DeskewAngle float64
+}
+
+// ResultWord is a word within a line.
+type ResultWord struct {
+	Result
+	// Language is the language the word was recognized with, e.g. "eng".
+	Language string
+	// FromDictionary is set, if the word was found in the dictionary of the language.
+	FromDictionary bool
+	// Numeric is set, if the word is a number.
+	Numeric bool
+	FontAttributes
+	Symbols []*ResultSymbol
+}
+
+// FontAttributes are the font of a word. Fonts are recognized by the legacy engine only:
+// with the LSTM engine, as in the embedded wasm, PointSize is the only one set,
+// the flags are false and FontName is empty.
+type FontAttributes struct {
+	IsBold       bool
+	IsItalic     bool
+	IsUnderlined bool
+	IsMonospace  bool
+	IsSerif      bool
+	IsSmallCaps  bool
+	// PointSize is the size of the font in printer's points, derived from the height
+	// of the line and the source resolution. It's 0 if the resolution is unknown.
+	PointSize int
+	FontName  string
+}
+
+// ResultSymbol is a character within a word.
+type ResultSymbol struct {
+	Result
+	Superscript bool
+	Subscript   bool
+	// DropCap is set for a large initial letter spanning several lines.
+	DropCap bool
+}
+
+// Text returns the text of the page, like Client.Text without trimming.
+func (page *ResultPage) Text() string {
+	var b strings.Builder
+	for _, block := range page.Blocks {
+		b.WriteString(block.Text)
+	}
+	return b.String()
+}
+
+// Words returns the words of all blocks of the page in reading order.
+func (page *ResultPage) Words() []*ResultWord {
+	var words []*ResultWord
+	for _, block := range page.Blocks {
+		for _, paragraph := range block.Paragraphs {
+			for _, line := range paragraph.Lines {
+				words = append(words, line.Words...)
+			}
+		}
+	}
+	return words
+}
+
+// Recognize recognizes the image once and returns all of the results as a tree,
+// from the blocks of the page down to the symbols of the words. Unlike calling Text
+// and GetBoundingBoxes one after the other, texts and boxes cannot disagree.
+func (client *Client) Recognize() (*ResultPage, error) {
+	return client.RecognizeContext(context.Background())
+}
+
+// RecognizeContext is Recognize with a context.Context, see TextContext for the cancellation behaviour.
+func (client *Client) RecognizeContext(ctx context.Context) (page *ResultPage, err error) {
+	if err = client.useContext(ctx); err != nil {
+		return
+	}
+	defer client.wasm.useContext(ctx)()
+	if err = client.init(); err != nil {
+		return
+	}
+	res, err := client.wasm.GetResults(client.api)
+	if err != nil {
+		return
+	}
+	records, err := client.readResults(res[0])
+	if err != nil {
+		return
+	}
+	return newResultPage(records), nil
+}
+
+// AnalyseLayout runs the layout analysis only, which is a lot faster than recognition,
+// e.g. to classify pages by their blocks. The tree goes down to the words, which have
+// no text or confidence. The ParagraphInfo of paragraphs is only known after recognition.
+// The page segmentation mode selects the analysis, PSM_AUTO_ONLY is the one of the command line.
+func (client *Client) AnalyseLayout() (*ResultPage, error) {
+	return client.AnalyseLayoutContext(context.Background())
+}
+
+// AnalyseLayoutContext is AnalyseLayout with a context.Context, see TextContext for the cancellation behaviour.
+func (client *Client) AnalyseLayoutContext(ctx context.Context) (page *ResultPage, err error) {
+	if err = client.useContext(ctx); err != nil {
+		return
+	}
+	defer client.wasm.useContext(ctx)()
+	if err = client.init(); err != nil {
+		return
+	}
+	res, err := client.wasm.AnalyseLayout(client.api)
+	if err != nil {
+		return
+	}
+	records, err := client.readResults(res[0])
+	if err != nil {
+		return
+	}
+	return newResultPage(records), nil
+}
+
+// resultRecord is a struct result read from the module memory.
+type resultRecord struct {
+	level PageIteratorLevel
+	Result
+	language                        string
+	fromDictionary, numeric         bool
+	superscript, subscript, dropcap bool
+	font                            FontAttributes
+	baseline                        *Baseline
+	orientation                     TextOrientation
+	paragraph                       ParagraphInfo
+	blockType                       PolyBlockType
+}
+
+// readResults reads the results returned by GetResults, and frees them.
+func (client *Client) readResults(resultsPtr uint64) ([]resultRecord, error) {
+	mem := client.wasm.module.Memory()
+	defer client.wasm.free(resultsPtr)
+	length, ok := mem.ReadUint32Le(uint32(resultsPtr))
+	if !ok {
+		return nil, &WasmError{Func: "GetResults", Kind: ErrWasmTrap, Err: fmt.Errorf("pointer %d is out of range", resultsPtr)}
+	}
+	itemsPtr, _ := mem.ReadUint32Le(uint32(resultsPtr) + 4)
+	defer client.wasm.free(uint64(itemsPtr))
+
+	items, ok := mem.Read(itemsPtr, length*resultSize)
+	if !ok {
+		return nil, &WasmError{Func: "GetResults", Kind: ErrWasmTrap, Err: fmt.Errorf("pointer %d is out of range", itemsPtr)}
+	}
+	// The view of the memory becomes invalid when it grows, so it's copied.
+	items = append([]byte(nil), items...)
+
+	records := make([]resultRecord, 0, length)
+	for i := 0; i < int(length); i++ {
+		item := items[i*resultSize : (i+1)*resultSize]
+		readInt := func(offset int) int {
+			return int(int32(uint32(item[offset]) | uint32(item[offset+1])<<8 | uint32(item[offset+2])<<16 | uint32(item[offset+3])<<24))
+		}
+
+		textPtr := uint64(uint32(readInt(resultText)))
+		text, err := client.wasm.ReadString(textPtr)
+		if err != nil {
+			return nil, err
+		}
+		if textPtr != 0 {
+			client.wasm.free(textPtr)
+		}
+		// The language and the font name are owned by tesseract, so they are not freed.
+		language, err := client.wasm.ReadString(uint64(uint32(readInt(resultLanguage))))
+		if err != nil {
+			return nil, err
+		}
+		fontName, err := client.wasm.ReadString(uint64(uint32(readInt(resultFontName))))
+		if err != nil {
+			return nil, err
+		}
+
+		var baseline *Baseline
+		if item[resultHasBaseline] != 0 {
+			baseline = &Baseline{
+				Start: image.Pt(readInt(resultBaseline), readInt(resultBaseline+4)),
+				End:   image.Pt(readInt(resultBaseline+8), readInt(resultBaseline+12)),
+			}
+		}
+
+		records = append(records, resultRecord{
+			level: PageIteratorLevel(readInt(resultLevel)),
+			Result: Result{
+				Box:        image.Rect(readInt(resultBox), readInt(resultBox+4), readInt(resultBox+8), readInt(resultBox+12)),
+				Text:       text,
+				Confidence: float64(math.Float32frombits(uint32(readInt(resultConfidence)))),
+			},
+			language:       language,
+			fromDictionary: item[resultFromDictionary] != 0,
+			numeric:        item[resultNumeric] != 0,
+			superscript:    item[resultSuperscript] != 0,
+			subscript:      item[resultSubscript] != 0,
+			dropcap:        item[resultDropcap] != 0,
+			font: FontAttributes{
+				IsBold:       item[resultBold] != 0,
+				IsItalic:     item[resultItalic] != 0,
+				IsUnderlined: item[resultUnderlined] != 0,
+				IsMonospace:  item[resultMonospace] != 0,
+				IsSerif:      item[resultSerif] != 0,
+				IsSmallCaps:  item[resultSmallCaps] != 0,
+				PointSize:    readInt(resultPointSize),
+				FontName:     fontName,
+			},
+			baseline:  baseline,
+			blockType: PolyBlockType(readInt(resultBlockType)),
+			orientation: TextOrientation{
+				Orientation:      Orientation(readInt(resultOrientation)),
+				WritingDirection: WritingDirection(readInt(resultWritingDir)),
+				TextlineOrder:    TextlineOrder(readInt(resultTextlineOrder)),
+				DeskewAngle:      float64(math.Float32frombits(uint32(readInt(resultDeskewAngle)))),
+			},
+			paragraph: ParagraphInfo{
+				Justification:   ParagraphJustification(readInt(resultJustification)),
+				IsListItem:      item[resultListItem] != 0,
+				IsCrown:         item[resultCrown] != 0,
+				FirstLineIndent: readInt(resultFirstIndent),
+			},
+		})
+	}
+	return records, nil
+}
+
+// newResultPage builds the tree from records in document order, where every element
+// is followed by its children.
+func newResultPage(records []resultRecord) *ResultPage {
+	page := &ResultPage{}
+	var (
+		block     *ResultBlock
+		paragraph *ResultParagraph
+		line      *ResultLine
+		word      *ResultWord
+	)
+	// The parents are created empty, if records are missing, which tesseract does not do.
+	for _, record := range records {
+		if record.level > RIL_BLOCK && block == nil {
+			block = &ResultBlock{}
+			page.Blocks = append(page.Blocks, block)
+		}
+		if record.level > RIL_PARA && paragraph == nil {
+			paragraph = &ResultParagraph{}
+			block.Paragraphs = append(block.Paragraphs, paragraph)
+		}
+		if record.level > RIL_TEXTLINE && line == nil {
+			line = &ResultLine{}
+			paragraph.Lines = append(paragraph.Lines, line)
+		}
+		if record.level > RIL_WORD && word == nil {
+			word = &ResultWord{}
+			line.Words = append(line.Words, word)
+		}
+
+		switch record.level {
+		case RIL_BLOCK:
+			block = &ResultBlock{Result: record.Result, BlockType: record.blockType}
+			page.Blocks = append(page.Blocks, block)
+			paragraph, line, word = nil, nil, nil
+		case RIL_PARA:
+			paragraph = &ResultParagraph{
+				Result:          record.Result,
+				ParagraphInfo:   record.paragraph,
+				TextOrientation: record.orientation,
+			}
+			block.Paragraphs = append(block.Paragraphs, paragraph)
+			line, word = nil, nil
+		case RIL_TEXTLINE:
+			line = &ResultLine{
+				Result:          record.Result,
+				Baseline:        record.baseline,
+				TextOrientation: record.orientation,
+			}
+			paragraph.Lines = append(paragraph.Lines, line)
+			word = nil
+		case RIL_WORD:
+			word = &ResultWord{
+				Result:         record.Result,
+				Language:       record.language,
+				FromDictionary: record.fromDictionary,
+				Numeric:        record.numeric,
+				FontAttributes: record.font,
+			}
+			line.Words = append(line.Words, word)
+		case RIL_SYMBOL:
+			word.Symbols = append(word.Symbols, &ResultSymbol{
+				Result:      record.Result,
+				Superscript: record.superscript,
+				Subscript:   record.subscript,
+				DropCap:     record.dropcap,
+			})
+		}
+	}
+	return page
+}
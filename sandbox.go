@@ -0,0 +1,193 @@
+package gosseract
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+)
+
+// HostFSMode selects how much of the host filesystem is visible to tesseract.
+type HostFSMode int
+
+const (
+	// HostFSRoot mounts the whole host filesystem at "/", which is the default.
+	HostFSRoot HostFSMode = iota
+	// HostFSNone mounts no host directory at all. Only the embedded tessdata and
+	// ClientOptions.FS are visible, so paths given to SetImage, SetConfigFile and
+	// SetTessdataPrefix are resolved inside ClientOptions.FS and must be relative.
+	HostFSNone
+	// HostFSReadOnlyDirs mounts ClientOptions.AllowedDirs read-only.
+	// Paths outside of these directories are rejected.
+	HostFSReadOnlyDirs
+)
+
+// ClientOptions configures the wasm module backing a Client.
+// See also New, which accepts these as Option.
+type ClientOptions struct {
+	// HostFS selects the exposure of the host filesystem, HostFSRoot as default.
+	HostFS HostFSMode
+
+	// AllowedDirs are the host directories mounted with HostFSReadOnlyDirs.
+	AllowedDirs []string
+
+	// FS is mounted at '/custom/' and can be used to provide (embedded) traineddata or other files.
+	// With HostFSNone, it's the only filesystem paths are resolved in.
+	FS fs.FS
+
+	// MemoryLimit is the maximum memory in bytes the wasm module may use, rounded down
+	// to whole 64KiB pages. If not specified, it's the 1GB the module is built with.
+	// It must be between 64KiB and the 4GiB wasm32 can address.
+	MemoryLimit uint64
+}
+
+// guestDirPrefix is where AllowedDirs are mounted inside the wasm module, followed by their index.
+// wazero only allows single-level guest paths, so the index is appended directly.
+const guestDirPrefix = "/host"
+
+// sandbox translates host paths into paths inside the wasm module.
+type sandbox struct {
+	mode HostFSMode
+	// dirs are the absolute, symlink free AllowedDirs.
+	dirs []string
+	fs   fs.FS
+}
+
+func newSandbox(opts ClientOptions) (*sandbox, error) {
+	s := &sandbox{mode: opts.HostFS, fs: opts.FS}
+	switch opts.HostFS {
+	case HostFSRoot, HostFSNone:
+	case HostFSReadOnlyDirs:
+		for _, dir := range opts.AllowedDirs {
+			abs, err := resolvePath(dir)
+			if err != nil {
+				return nil, fmt.Errorf("cannot resolve allowed dir: %w", err)
+			}
+			info, err := os.Stat(abs)
+			if err != nil {
+				return nil, fmt.Errorf("cannot detect the stat of allowed dir: %w", err)
+			}
+			if !info.IsDir() {
+				return nil, fmt.Errorf("allowed dir %s is not a directory", dir)
+			}
+			s.dirs = append(s.dirs, abs)
+		}
+	default:
+		return nil, fmt.Errorf("unknown HostFSMode %d", opts.HostFS)
+	}
+	return s, nil
+}
+
+// fsConfig mounts the directories of the sandbox, besides the embedded tessdata.
+func (s *sandbox) fsConfig() wazero.FSConfig {
+	config := wazero.NewFSConfig()
+	switch s.mode {
+	case HostFSRoot:
+		config = config.WithDirMount("/", "/")
+	case HostFSReadOnlyDirs:
+		for i, dir := range s.dirs {
+			config = config.WithReadOnlyDirMount(dir, guestDirPrefix+strconv.Itoa(i))
+		}
+	}
+	return config.
+		WithFSMount(languages, "/tessdata/").
+		WithFSMount(s.fs, "/custom/")
+}
+
+// stat returns the FileInfo of the host path p, or an error if p is not visible to tesseract.
+func (s *sandbox) stat(p string) (fs.FileInfo, error) {
+	if s.mode == HostFSNone {
+		name, err := s.fsName(p)
+		if err != nil {
+			return nil, err
+		}
+		return fs.Stat(s.fs, name)
+	}
+	if _, err := s.guestPath(p); err != nil {
+		return nil, err
+	}
+	return os.Stat(p)
+}
+
+// readHead reads the first sniffLen bytes of the file at the host path p.
+func (s *sandbox) readHead(p string) ([]byte, error) {
+	var file io.ReadCloser
+	var err error
+	if s.mode == HostFSNone {
+		var name string
+		if name, err = s.fsName(p); err == nil {
+			file, err = s.fs.Open(name)
+		}
+	} else {
+		file, err = os.Open(p)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// guestPath translates the host path p into the path tesseract sees inside the wasm module.
+func (s *sandbox) guestPath(p string) (string, error) {
+	switch s.mode {
+	case HostFSNone:
+		name, err := s.fsName(p)
+		if err != nil {
+			return "", err
+		}
+		return path.Join("/custom", name), nil
+	case HostFSReadOnlyDirs:
+		abs, err := resolvePath(p)
+		if err != nil {
+			return "", err
+		}
+		for i, dir := range s.dirs {
+			rel, err := filepath.Rel(dir, abs)
+			if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			return path.Join(guestDirPrefix+strconv.Itoa(i), filepath.ToSlash(rel)), nil
+		}
+		return "", fmt.Errorf("%w: %s", ErrPathNotAllowed, p)
+	default:
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return "", err
+		}
+		return filepath.ToSlash(abs), nil
+	}
+}
+
+// fsName converts the relative path p into a name which is valid for ClientOptions.FS.
+func (s *sandbox) fsName(p string) (string, error) {
+	name := path.Clean(filepath.ToSlash(p))
+	if s.fs == nil || filepath.IsAbs(p) || !fs.ValidPath(name) {
+		return "", fmt.Errorf("%w: %s", ErrPathNotAllowed, p)
+	}
+	return name, nil
+}
+
+// resolvePath makes p absolute and follows symlinks, so a link cannot point out of an allowed dir.
+// Paths which do not exist yet are only made absolute.
+func resolvePath(p string) (string, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved, nil
+	}
+	return abs, nil
+}
@@ -9,6 +9,7 @@
 #include "tessbridge.h"
 #include <filesystem>
 #include <stdio.h>
+#include <string.h>
 #include <unistd.h>
 
 TessBaseAPI Create() {
@@ -42,34 +43,60 @@ int Init(TessBaseAPI a, char *tessdataprefix, char *languages) {
 }
 
 int Init(TessBaseAPI a, char *tessdataprefix, char *languages,
-         char *configfilepath, char *errbuf) {
+         char *configfilepath, int oem) {
   tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
+  tesseract::OcrEngineMode mode = (tesseract::OcrEngineMode)oem;
   int ret;
   if (configfilepath != NULL) {
     char *configs[] = {configfilepath};
     int configs_size = 1;
-    ret = api->Init(tessdataprefix, languages, tesseract::OEM_DEFAULT, configs,
-                    configs_size, NULL, NULL, false);
+    ret = api->Init(tessdataprefix, languages, mode, configs, configs_size,
+                    NULL, NULL, false);
   } else {
-    ret = api->Init(tessdataprefix, languages);
+    ret = api->Init(tessdataprefix, languages, mode);
   }
   return ret;
 }
 
+bool HasLegacyEngine() {
+#ifdef DISABLED_LEGACY_ENGINE
+  return false;
+#else
+  return true;
+#endif
+}
+
 bool SetVariable(TessBaseAPI a, char *name, char *value) {
   tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
   return api->SetVariable(name, value);
 }
 
-void SetPixImage(TessBaseAPI a, PixImage pix) {
+// SetPixImage sets the image, and raises its resolution to 70 DPI if it is lower
+// and clamp is set.
+void SetPixImage(TessBaseAPI a, PixImage pix, bool clamp) {
   tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
   Pix *image = (Pix *)pix;
   api->SetImage(image);
-  if (api->GetSourceYResolution() < 70) {
+  if (clamp && api->GetSourceYResolution() < 70) {
     api->SetSourceResolution(70);
   }
 }
 
+void SetRectangle(TessBaseAPI a, int left, int top, int width, int height) {
+  tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
+  api->SetRectangle(left, top, width, height);
+}
+
+void SetSourceResolution(TessBaseAPI a, int ppi) {
+  tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
+  api->SetSourceResolution(ppi);
+}
+
+int GetSourceResolution(TessBaseAPI a) {
+  tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
+  return api->GetSourceYResolution();
+}
+
 void SetPageSegMode(TessBaseAPI a, int m) {
   tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
   tesseract::PageSegMode mode = (tesseract::PageSegMode)m;
@@ -91,6 +118,43 @@ char *HOCRText(TessBaseAPI a) {
   return api->GetHOCRText(0);
 }
 
+char *TSVText(TessBaseAPI a, int page_number) {
+  tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
+  return api->GetTSVText(page_number);
+}
+
+char *BoxText(TessBaseAPI a) {
+  tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
+  return api->GetBoxText(0);
+}
+
+char *LSTMBoxText(TessBaseAPI a) {
+  tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
+  return api->GetLSTMBoxText(0);
+}
+
+char *WordStrBoxText(TessBaseAPI a) {
+  tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
+  return api->GetWordStrBoxText(0);
+}
+
+int *AllWordConfidences(TessBaseAPI a) {
+  tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
+  return api->AllWordConfidences();
+}
+
+// RecognizeOnce recognizes the image unless it was recognized since it was set,
+// like the getters of TessBaseAPI do, so the results of Text are reused.
+// AllWordConfidences is the cheapest of these getters.
+static int RecognizeOnce(tesseract::TessBaseAPI *api) {
+  int *confidences = api->AllWordConfidences();
+  if (confidences == nullptr) {
+    return -1;
+  }
+  delete[] confidences;
+  return 0;
+}
+
 bounding_boxes *GetBoundingBoxesVerbose(TessBaseAPI a) {
   using namespace tesseract;
   tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
@@ -102,7 +166,7 @@ bounding_boxes *GetBoundingBoxesVerbose(TessBaseAPI a) {
   int capacity = 1000;
   box_array->boxes = (bounding_box *)malloc(capacity * sizeof(bounding_box));
   box_array->length = 0;
-  api->Recognize(NULL);
+  RecognizeOnce(api);
   int block_num = 0;
   int par_num = 0;
   int line_num = 0;
@@ -170,7 +234,7 @@ bounding_boxes *GetBoundingBoxes(TessBaseAPI a, int pageIteratorLevel) {
   int capacity = 1000;
   box_array->boxes = (bounding_box *)malloc(capacity * sizeof(bounding_box));
   box_array->length = 0;
-  api->Recognize(NULL);
+  RecognizeOnce(api);
   tesseract::ResultIterator *ri = api->GetIterator();
   tesseract::PageIteratorLevel level =
       (tesseract::PageIteratorLevel)pageIteratorLevel;
@@ -196,6 +260,232 @@ bounding_boxes *GetBoundingBoxes(TessBaseAPI a, int pageIteratorLevel) {
   return box_array;
 }
 
+// AddLayout adds the element of it at level, with what is known without
+// recognition.
+static result *AddLayout(results *result_array, int *capacity,
+                         tesseract::PageIterator *it,
+                         tesseract::PageIteratorLevel level) {
+  using namespace tesseract;
+  if (result_array->length >= *capacity) {
+    *capacity *= 2;
+    result_array->items =
+        (result *)realloc(result_array->items, *capacity * sizeof(result));
+  }
+  result *r = &result_array->items[result_array->length++];
+  memset(r, 0, sizeof(result));
+  r->level = level;
+  it->BoundingBox(level, &r->x1, &r->y1, &r->x2, &r->y2);
+  if (level == RIL_BLOCK) {
+    r->block_type = it->BlockType();
+  }
+  if (level == RIL_PARA || level == RIL_TEXTLINE) {
+    Orientation orientation;
+    WritingDirection writing_direction;
+    TextlineOrder textline_order;
+    it->Orientation(&orientation, &writing_direction, &textline_order,
+                    &r->deskew_angle);
+    r->orientation = orientation;
+    r->writing_direction = writing_direction;
+    r->textline_order = textline_order;
+  }
+  if (level == RIL_PARA) {
+    ParagraphJustification justification;
+    it->ParagraphInfo(&justification, &r->list_item, &r->crown,
+                      &r->first_line_indent);
+    r->justification = justification;
+  }
+  if (level == RIL_TEXTLINE) {
+    r->has_baseline = it->Baseline(RIL_TEXTLINE, &r->baseline_x1,
+                                   &r->baseline_y1, &r->baseline_x2,
+                                   &r->baseline_y2);
+  }
+  return r;
+}
+
+// AddResult adds the element of res_it at level, with its text.
+static result *AddResult(results *result_array, int *capacity,
+                         tesseract::ResultIterator *res_it,
+                         tesseract::PageIteratorLevel level) {
+  result *r = AddLayout(result_array, capacity, res_it, level);
+  r->text = res_it->GetUTF8Text(level);
+  r->confidence = res_it->Confidence(level);
+  return r;
+}
+
+static results *NewResults(int capacity) {
+  struct results *result_array;
+  result_array = (results *)malloc(sizeof(results));
+  result_array->items = (result *)malloc(capacity * sizeof(result));
+  result_array->length = 0;
+  return result_array;
+}
+
+// GetResults recognizes the image once and flattens the whole ResultIterator
+// hierarchy in document order: every element is followed by its children.
+results *GetResults(TessBaseAPI a) {
+  using namespace tesseract;
+  tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
+  int capacity = 1000;
+  struct results *result_array = NewResults(capacity);
+  if (RecognizeOnce(api) < 0) {
+    return result_array;
+  }
+
+  ResultIterator *res_it = api->GetIterator();
+  if (res_it == nullptr) {
+    return result_array;
+  }
+  while (!res_it->Empty(RIL_BLOCK)) {
+    if (res_it->Empty(RIL_WORD)) {
+      // Blocks without text, e.g. images, have no children.
+      AddResult(result_array, &capacity, res_it, RIL_BLOCK);
+      res_it->Next(RIL_WORD);
+      continue;
+    }
+    if (res_it->IsAtBeginningOf(RIL_BLOCK)) {
+      AddResult(result_array, &capacity, res_it, RIL_BLOCK);
+    }
+    if (res_it->IsAtBeginningOf(RIL_PARA)) {
+      AddResult(result_array, &capacity, res_it, RIL_PARA);
+    }
+    if (res_it->IsAtBeginningOf(RIL_TEXTLINE)) {
+      AddResult(result_array, &capacity, res_it, RIL_TEXTLINE);
+    }
+    result *word = AddResult(result_array, &capacity, res_it, RIL_WORD);
+    word->language = res_it->WordRecognitionLanguage();
+    word->from_dictionary = res_it->WordIsFromDictionary();
+    word->numeric = res_it->WordIsNumeric();
+    int font_id;
+    word->font_name = res_it->WordFontAttributes(
+        &word->bold, &word->italic, &word->underlined, &word->monospace,
+        &word->serif, &word->smallcaps, &word->point_size, &font_id);
+
+    for (;;) {
+      result *symbol = AddResult(result_array, &capacity, res_it, RIL_SYMBOL);
+      symbol->superscript = res_it->SymbolIsSuperscript();
+      symbol->subscript = res_it->SymbolIsSubscript();
+      symbol->dropcap = res_it->SymbolIsDropcap();
+      if (res_it->IsAtFinalElement(RIL_WORD, RIL_SYMBOL)) {
+        break;
+      }
+      res_it->Next(RIL_SYMBOL);
+    }
+    res_it->Next(RIL_WORD);
+  }
+  delete res_it;
+
+  return result_array;
+}
+
+// AnalyseLayout runs the layout analysis only, and flattens the PageIterator
+// hierarchy down to the words like GetResults, without text.
+results *AnalyseLayout(TessBaseAPI a) {
+  using namespace tesseract;
+  tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
+  int capacity = 1000;
+  struct results *result_array = NewResults(capacity);
+
+  PageIterator *it = api->AnalyseLayout();
+  if (it == nullptr) {
+    return result_array;
+  }
+  while (!it->Empty(RIL_BLOCK)) {
+    if (it->Empty(RIL_WORD)) {
+      AddLayout(result_array, &capacity, it, RIL_BLOCK);
+      it->Next(RIL_WORD);
+      continue;
+    }
+    if (it->IsAtBeginningOf(RIL_BLOCK)) {
+      AddLayout(result_array, &capacity, it, RIL_BLOCK);
+    }
+    if (it->IsAtBeginningOf(RIL_PARA)) {
+      AddLayout(result_array, &capacity, it, RIL_PARA);
+    }
+    if (it->IsAtBeginningOf(RIL_TEXTLINE)) {
+      AddLayout(result_array, &capacity, it, RIL_TEXTLINE);
+    }
+    AddLayout(result_array, &capacity, it, RIL_WORD);
+    it->Next(RIL_WORD);
+  }
+  delete it;
+
+  return result_array;
+}
+
+// Kinds of the records of GetSymbolChoices.
+enum {
+  CHOICE_SYMBOL,
+  CHOICE_ALTERNATIVE,
+  CHOICE_TIMESTEP,
+  CHOICE_TIMESTEP_ALTERNATIVE
+};
+
+static choice *AddChoice(choices *choice_array, int *capacity, int kind,
+                         const char *text, float confidence) {
+  if (choice_array->length >= *capacity) {
+    *capacity *= 2;
+    choice_array->items =
+        (choice *)realloc(choice_array->items, *capacity * sizeof(choice));
+  }
+  choice *c = &choice_array->items[choice_array->length++];
+  memset(c, 0, sizeof(choice));
+  c->kind = kind;
+  c->text = text != nullptr ? strdup(text) : nullptr;
+  c->confidence = confidence;
+  return c;
+}
+
+// GetSymbolChoices recognizes the image and lists every symbol followed by its
+// alternatives, and by its LSTM timesteps, if lstm_choice_mode is set.
+choices *GetSymbolChoices(TessBaseAPI a) {
+  using namespace tesseract;
+  tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
+  struct choices *choice_array;
+  choice_array = (choices *)malloc(sizeof(choices));
+  int capacity = 1000;
+  choice_array->items = (choice *)malloc(capacity * sizeof(choice));
+  choice_array->length = 0;
+  if (RecognizeOnce(api) < 0) {
+    return choice_array;
+  }
+
+  ResultIterator *res_it = api->GetIterator();
+  if (res_it == nullptr) {
+    return choice_array;
+  }
+  do {
+    if (res_it->Empty(RIL_SYMBOL)) {
+      continue;
+    }
+    char *text = res_it->GetUTF8Text(RIL_SYMBOL);
+    choice *symbol = AddChoice(choice_array, &capacity, CHOICE_SYMBOL, text,
+                               res_it->Confidence(RIL_SYMBOL));
+    delete[] text;
+    res_it->BoundingBox(RIL_SYMBOL, &symbol->x1, &symbol->y1, &symbol->x2,
+                        &symbol->y2);
+
+    ChoiceIterator choice_it(*res_it);
+    do {
+      AddChoice(choice_array, &capacity, CHOICE_ALTERNATIVE,
+                choice_it.GetUTF8Text(), choice_it.Confidence());
+    } while (choice_it.Next());
+
+    auto *timesteps = choice_it.Timesteps();
+    if (timesteps != nullptr) {
+      for (auto &timestep : *timesteps) {
+        AddChoice(choice_array, &capacity, CHOICE_TIMESTEP, nullptr, 0);
+        for (auto &alternative : timestep) {
+          AddChoice(choice_array, &capacity, CHOICE_TIMESTEP_ALTERNATIVE,
+                    alternative.first, alternative.second);
+        }
+      }
+    }
+  } while (res_it->Next(RIL_SYMBOL));
+  delete res_it;
+
+  return choice_array;
+}
+
 const char *Version(TessBaseAPI a) {
   tesseract::TessBaseAPI *api = (tesseract::TessBaseAPI *)a;
   const char *v = api->Version();
@@ -212,6 +502,38 @@ PixImage CreatePixImageFromBytes(unsigned char *data, int size) {
   return (void *)image;
 }
 
+PixImage CreatePix(int width, int height, int depth) {
+  Pix *image = pixCreateNoInit(width, height, depth);
+  if (image != nullptr && depth == 32) {
+    pixSetSpp(image, 3);
+  }
+  return (void *)image;
+}
+
+// GetPixInfo describes pix, so its raster can be read and written without
+// knowing the layout of struct Pix. The colormap is a copy in RGB triples.
+pix_info *GetPixInfo(PixImage pix) {
+  Pix *image = (Pix *)pix;
+  pix_info *info = (pix_info *)malloc(sizeof(pix_info));
+  memset(info, 0, sizeof(pix_info));
+  pixGetDimensions(image, &info->width, &info->height, &info->depth);
+  info->wpl = pixGetWpl(image);
+  info->data = pixGetData(image);
+  PIXCMAP *cmap = pixGetColormap(image);
+  if (cmap != nullptr) {
+    info->colormap_length = pixcmapGetCount(cmap);
+    info->colormap = (unsigned char *)malloc(3 * info->colormap_length);
+    for (int i = 0; i < info->colormap_length; i++) {
+      int r, g, b;
+      pixcmapGetColor(cmap, i, &r, &g, &b);
+      info->colormap[3 * i] = r;
+      info->colormap[3 * i + 1] = g;
+      info->colormap[3 * i + 2] = b;
+    }
+  }
+  return info;
+}
+
 void DestroyPixImage(PixImage pix) {
   Pix *img = (Pix *)pix;
   pixDestroy(&img);
@@ -17,25 +17,82 @@ struct bounding_boxes {
   struct bounding_box *boxes;
 };
 
+struct result {
+  int level;
+  int x1, y1, x2, y2;
+  char *text;
+  float confidence;
+  const char *language;
+  bool from_dictionary, numeric;
+  bool superscript, subscript, dropcap;
+  bool bold, italic, underlined, monospace, serif, smallcaps;
+  int point_size;
+  const char *font_name;
+  int baseline_x1, baseline_y1, baseline_x2, baseline_y2;
+  int orientation, writing_direction, textline_order;
+  float deskew_angle;
+  int justification, first_line_indent;
+  bool has_baseline, list_item, crown;
+  int block_type;
+};
+
+struct pix_info {
+  int width, height, depth, wpl;
+  unsigned int *data;
+  int colormap_length;
+  unsigned char *colormap;
+};
+
+struct results {
+  int length;
+  struct result *items;
+};
+
+struct choice {
+  int kind;
+  int x1, y1, x2, y2;
+  char *text;
+  float confidence;
+};
+
+struct choices {
+  int length;
+  struct choice *items;
+};
+
 TessBaseAPI Create(void);
 
 void Free(TessBaseAPI);
 void Clear(TessBaseAPI);
 void ClearPersistentCache(TessBaseAPI);
-int Init(TessBaseAPI, char *, char *, char *, char *);
+int Init(TessBaseAPI, char *, char *, char *, int);
+bool HasLegacyEngine(void);
 struct bounding_boxes *GetBoundingBoxes(TessBaseAPI, int);
 struct bounding_boxes *GetBoundingBoxesVerbose(TessBaseAPI);
+struct results *GetResults(TessBaseAPI);
+struct results *AnalyseLayout(TessBaseAPI);
+struct choices *GetSymbolChoices(TessBaseAPI);
 bool SetVariable(TessBaseAPI, char *, char *);
-void SetPixImage(TessBaseAPI a, PixImage pix);
+void SetPixImage(TessBaseAPI a, PixImage pix, bool clamp);
+void SetRectangle(TessBaseAPI, int, int, int, int);
+void SetSourceResolution(TessBaseAPI, int);
+int GetSourceResolution(TessBaseAPI);
 void SetPageSegMode(TessBaseAPI, int);
 int GetPageSegMode(TessBaseAPI);
 char *UTF8Text(TessBaseAPI);
 char *HOCRText(TessBaseAPI);
+char *TSVText(TessBaseAPI, int);
+char *BoxText(TessBaseAPI);
+char *LSTMBoxText(TessBaseAPI);
+char *WordStrBoxText(TessBaseAPI);
+int *AllWordConfidences(TessBaseAPI);
 const char *Version(TessBaseAPI);
 const char *GetDataPath();
 
 PixImage CreatePixImageByFilePath(char *);
 PixImage CreatePixImageFromBytes(unsigned char *, int);
+PixImage CreatePix(int, int, int);
+struct pix_info *GetPixInfo(PixImage);
 void DestroyPixImage(PixImage pix);
 bool FileExists(char *filepath);
 
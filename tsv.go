@@ -0,0 +1,113 @@
+package gosseract
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TSVHeader is the first line of the TSV written by the tesseract command line, followed by the rows
+// of every page, see TSVText.
+const TSVHeader = "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n"
+
+// Levels of TSV rows.
+const (
+	TSVLevelPage = iota + 1
+	TSVLevelBlock
+	TSVLevelParagraph
+	TSVLevelLine
+	TSVLevelWord
+)
+
+// TSVRow is a row of TSV, see TSVText.
+type TSVRow struct {
+	// Level is the level of the element, see TSVLevelPage and the following constants.
+	Level int
+	// PageNum starts at 1, the other numbers are 1-based within their parent element,
+	// and 0 for the levels above the element.
+	PageNum, BlockNum, ParNum, LineNum, WordNum int
+	// Box is the bounding box of the element.
+	Box image.Rectangle
+	// Conf is the confidence of a word between 0 and 100, -1 for the other levels.
+	Conf float64
+	// Text is the text of a word, empty for the other levels.
+	Text string
+}
+
+// TSVText recognizes the image and returns the result as tab separated values, with rows for the
+// page, blocks, paragraphs, lines and words. pageNumber is the 0-based number of the image,
+// the page_num column is pageNumber+1. TSVHeader followed by TSVText(0) has the columns and
+// rows of the output of `tesseract image out tsv`.
+func (client *Client) TSVText(pageNumber int) (string, error) {
+	return client.TSVTextContext(context.Background(), pageNumber)
+}
+
+// TSVTextContext is TSVText with a context.Context, see TextContext for the cancellation behaviour.
+func (client *Client) TSVTextContext(ctx context.Context, pageNumber int) (out string, err error) {
+	if pageNumber < 0 {
+		return "", fmt.Errorf("invalid page number %d", pageNumber)
+	}
+	if err = client.useContext(ctx); err != nil {
+		return
+	}
+	defer client.wasm.useContext(ctx)()
+	if err = client.init(); err != nil {
+		return
+	}
+	res, err := client.wasm.TSVText(client.api, uint64(pageNumber))
+	if err != nil {
+		return
+	}
+	if res[0] == 0 {
+		return "", fmt.Errorf("failed to recognize the image")
+	}
+	defer client.wasm.free(res[0])
+	return client.wasm.ReadString(res[0])
+}
+
+// ParseTSV reads the rows of TSV as returned by TSVText. The header line is skipped, so the
+// output of the tesseract command line can be read as well.
+func ParseTSV(r io.Reader) ([]TSVRow, error) {
+	var rows []TSVRow
+	reader := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"); line != "" && !(n == 1 && line+"\n" == TSVHeader) {
+			row, parseErr := parseTSVRow(line)
+			if parseErr != nil {
+				return nil, fmt.Errorf("failed to parse line %d of TSV: %w", n, parseErr)
+			}
+			rows = append(rows, row)
+		}
+		if err == io.EOF {
+			return rows, nil
+		}
+	}
+}
+
+func parseTSVRow(line string) (row TSVRow, err error) {
+	columns := strings.SplitN(line, "\t", 12)
+	if len(columns) != 12 {
+		return row, fmt.Errorf("expected 12 columns, got %d", len(columns))
+	}
+	var ints [10]int
+	for i := range ints {
+		if ints[i], err = strconv.Atoi(columns[i]); err != nil {
+			return
+		}
+	}
+	if row.Conf, err = strconv.ParseFloat(columns[10], 64); err != nil {
+		return
+	}
+	row.Level, row.PageNum, row.BlockNum, row.ParNum, row.LineNum, row.WordNum = ints[0], ints[1], ints[2], ints[3], ints[4], ints[5]
+	row.Box = image.Rect(ints[6], ints[7], ints[6]+ints[8], ints[7]+ints[9])
+	row.Text = columns[11]
+	return row, nil
+}
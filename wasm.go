@@ -4,8 +4,8 @@ import (
 	"bytes"
 	"context"
 	"embed"
-	"io/fs"
-	"log"
+	"fmt"
+	"io"
 	"math"
 	"os"
 	"sync"
@@ -14,120 +14,203 @@ import (
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/experimental/logging"
-	"github.com/tetratelabs/wazero/imports/emscripten"
-	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 )
 
 //go:embed build/tesseract-core.wasm
 var binary []byte
 
-var compiledModule wazero.CompiledModule
+// defaultEngine is used by the package level functions, see getDefaultEngine.
+var defaultEngine *Engine
+
+// runtimeConfig is the config of defaultEngine, see SetCompilationCacheDir.
 var runtimeConfig wazero.RuntimeConfig
-var r wazero.Runtime
 var ctx context.Context
 var initLock = &sync.Mutex{}
 
+// wasmPageSize is the size of a page of wasm memory.
+const wasmPageSize = 65536
+
+// maxMemoryLimit is the 4GiB wasm32 can address at most.
+const maxMemoryLimit = 65536 * wasmPageSize
+
+// checkMemoryLimit reports limits which cannot be passed to wazero, see ClientOptions.MemoryLimit.
+func checkMemoryLimit(limit uint64) error {
+	if limit < wasmPageSize {
+		return fmt.Errorf("memory limit must be at least %d bytes", wasmPageSize)
+	}
+	if limit > maxMemoryLimit {
+		return fmt.Errorf("memory limit must be at most %d bytes", uint64(maxMemoryLimit))
+	}
+	return nil
+}
+
 //go:embed eng.traineddata
 var languages embed.FS
 
-func newApi() *tesseractApi {
-	return newApiWithFS(nil)
+func newApi() (*tesseractApi, error) {
+	return newApiWithOptions(ClientOptions{})
 }
 
-func newApiWithFS(fs fs.FS) *tesseractApi {
-	func() {
-		initLock.Lock()
-		defer initLock.Unlock()
-		if r == nil {
-			ctx = context.WithValue(context.Background(), experimental.FunctionListenerFactoryKey{}, logging.NewLoggingListenerFactory(os.Stdout))
-			ctx = context.Background() // Comment this line to get debug information.
+func newApiWithOptions(opts ClientOptions) (*tesseractApi, error) {
+	engine, err := getDefaultEngine()
+	if err != nil {
+		return nil, err
+	}
+	return engine.newApi(opts)
+}
 
-			if runtimeConfig == nil {
-				cache := wazero.NewCompilationCache()
-				runtimeConfig = wazero.NewRuntimeConfig().WithCompilationCache(cache)
+// getDefaultEngine lazily creates the engine shared by NewClient and friends.
+func getDefaultEngine() (*Engine, error) {
+	initLock.Lock()
+	defer initLock.Unlock()
+	if defaultEngine != nil {
+		return defaultEngine, nil
+	}
+
+	if runtimeConfig == nil {
+		cache := wazero.NewCompilationCache()
+		if dir := os.Getenv(CompilationCacheDirEnv); dir != "" {
+			var err error
+			if cache, err = wazero.NewCompilationCacheWithDir(dir); err != nil {
+				return nil, fmt.Errorf("failed to open compilation cache dir: %w", err)
 			}
+		}
+		runtimeConfig = newRuntimeConfig(cache)
+	}
 
-			// Create a new WebAssembly Runtime.
-			r = wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+	engine, err := NewEngine(EngineConfig{RuntimeConfig: runtimeConfig})
+	if err != nil {
+		return nil, err
+	}
+	defaultEngine = engine
+	return defaultEngine, nil
+}
 
-			wasi_snapshot_preview1.MustInstantiate(ctx, r)
+// CompilationCacheDirEnv is the environment variable which can point to a directory
+// to keep the compiled wasm in, see SetCompilationCacheDir.
+const CompilationCacheDirEnv = "GOSSERACT_COMPILATION_CACHE_DIR"
 
-			if compiledModule == nil {
-				module, err := r.CompileModule(ctx, binary)
-				compiledModule = module
-				if err != nil {
-					log.Panicf("failed to compile module: %v", err)
-				}
-			}
-			_, err := emscripten.InstantiateForModule(ctx, r, compiledModule)
-			if err != nil {
-				log.Panicf("failed to instantiate module (emscripten): %v", err)
-			}
+// SetCompilationCacheDir keeps the compiled wasm in dir, so it's compiled only once
+// instead of on every start of the process. It takes precedence over CompilationCacheDirEnv,
+// but has to be called before the first client is created.
+// Engines created by NewEngine use EngineConfig.CompilationCacheDir instead.
+func SetCompilationCacheDir(dir string) error {
+	initLock.Lock()
+	defer initLock.Unlock()
+	if defaultEngine != nil {
+		return fmt.Errorf("the compilation cache dir must be set before the first client is created")
+	}
+	cache, err := wazero.NewCompilationCacheWithDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open compilation cache dir: %w", err)
+	}
+	runtimeConfig = newRuntimeConfig(cache)
+	return nil
+}
 
-		}
-	}()
+func newRuntimeConfig(cache wazero.CompilationCache) wazero.RuntimeConfig {
+	// CloseOnContextDone lets a canceled context.Context abort a running recognition.
+	return wazero.NewRuntimeConfig().WithCompilationCache(cache).WithCloseOnContextDone(true)
+}
 
-	mod, err := r.InstantiateModule(ctx, compiledModule, wazero.NewModuleConfig().
-		WithStartFunctions("_initialize").
-		WithFSConfig(
-			wazero.NewFSConfig().
-				WithDirMount("/", "/").
-				WithFSMount(languages, "/tessdata/").
-				WithFSMount(fs, "/custom/")))
+func init() {
+	ctx = context.WithValue(context.Background(), experimental.FunctionListenerFactoryKey{}, logging.NewLoggingListenerFactory(os.Stdout))
+	ctx = context.Background() // Comment this line to get debug information.
+}
 
-	if err != nil {
-		log.Panicf("failed to instantiate module: %v", err)
-	}
-	tAPI := tesseractApi{
-		module:                   mod,
-		context:                  ctx,
-		Create:                   fun(ctx, mod, "Create"),
-		Free:                     fun(ctx, mod, "Free"),
-		free:                     fun(ctx, mod, "free"),
-		malloc:                   fun(ctx, mod, "malloc"),
-		Clear:                    fun(ctx, mod, "Clear"),
-		ClearPersistentCache:     fun(ctx, mod, "ClearPersistentCache"),
-		Init:                     fun(ctx, mod, "Init"),
-		GetBoundingBoxes:         fun(ctx, mod, "GetBoundingBoxes"),
-		GetBoundingBoxesVerbose:  fun(ctx, mod, "GetBoundingBoxesVerbose"),
-		SetVariable:              fun(ctx, mod, "SetVariable"),
-		SetPixImage:              fun(ctx, mod, "SetPixImage"),
-		SetPageSegMode:           fun(ctx, mod, "SetPageSegMode"),
-		GetPageSegMode:           fun(ctx, mod, "GetPageSegMode"),
-		Utf8Text:                 fun(ctx, mod, "UTF8Text"),
-		HocrText:                 fun(ctx, mod, "HOCRText"),
-		Version:                  fun(ctx, mod, "Version"),
-		GetDataPath:              fun(ctx, mod, "GetDataPath"),
-		CreatePixImageByFilepath: fun(ctx, mod, "CreatePixImageByFilePath"),
-		CreatePixImageFromBytes:  fun(ctx, mod, "CreatePixImageFromBytes"),
-		DestroyPixImage:          fun(ctx, mod, "DestroyPixImage"),
-		FileExists:               fun(ctx, mod, "FileExists"),
+// newTesseractApi binds the functions of the instantiated module mod.
+// runtime is only given if it is owned by the module.
+func newTesseractApi(mod api.Module, runtime wazero.Runtime, sandbox *sandbox) (*tesseractApi, error) {
+	tAPI := &tesseractApi{
+		module:  mod,
+		runtime: runtime,
+		context: ctx,
+		sandbox: sandbox,
 	}
+	tAPI.Create = tAPI.fun("Create")
+	tAPI.Free = tAPI.fun("Free")
+	tAPI.free = tAPI.fun("free")
+	tAPI.malloc = tAPI.fun("malloc")
+	tAPI.Clear = tAPI.fun("Clear")
+	tAPI.ClearPersistentCache = tAPI.fun("ClearPersistentCache")
+	tAPI.Init = tAPI.fun("Init")
+	tAPI.GetBoundingBoxes = tAPI.fun("GetBoundingBoxes")
+	tAPI.GetBoundingBoxesVerbose = tAPI.fun("GetBoundingBoxesVerbose")
+	tAPI.GetResults = tAPI.fun("GetResults")
+	tAPI.AnalyseLayout = tAPI.fun("AnalyseLayout")
+	tAPI.GetSymbolChoices = tAPI.fun("GetSymbolChoices")
+	tAPI.SetVariable = tAPI.fun("SetVariable")
+	tAPI.SetPixImage = tAPI.fun("SetPixImage")
+	tAPI.SetRectangle = tAPI.fun("SetRectangle")
+	tAPI.SetSourceResolution = tAPI.fun("SetSourceResolution")
+	tAPI.GetSourceResolution = tAPI.fun("GetSourceResolution")
+	tAPI.SetPageSegMode = tAPI.fun("SetPageSegMode")
+	tAPI.GetPageSegMode = tAPI.fun("GetPageSegMode")
+	tAPI.Utf8Text = tAPI.fun("UTF8Text")
+	tAPI.HocrText = tAPI.fun("HOCRText")
+	tAPI.TSVText = tAPI.fun("TSVText")
+	tAPI.BoxText = tAPI.fun("BoxText")
+	tAPI.LSTMBoxText = tAPI.fun("LSTMBoxText")
+	tAPI.WordStrBoxText = tAPI.fun("WordStrBoxText")
+	tAPI.AllWordConfidences = tAPI.fun("AllWordConfidences")
+	tAPI.Version = tAPI.fun("Version")
+	tAPI.GetDataPath = tAPI.fun("GetDataPath")
+	tAPI.CreatePixImageByFilepath = tAPI.fun("CreatePixImageByFilePath")
+	tAPI.CreatePixImageFromBytes = tAPI.fun("CreatePixImageFromBytes")
+	tAPI.CreatePix = tAPI.fun("CreatePix")
+	tAPI.GetPixInfo = tAPI.fun("GetPixInfo")
+	tAPI.DestroyPixImage = tAPI.fun("DestroyPixImage")
+	tAPI.FileExists = tAPI.fun("FileExists")
+	tAPI.HasLegacyEngine = tAPI.fun("HasLegacyEngine")
 
 	// try calling file exists method, to check if everything is working
-	_, err = mod.ExportedFunction("FileExists").Call(ctx, 0)
-	if err != nil {
-		log.Panicln("could not load wasm module")
+	if _, err := tAPI.FileExists(0); err != nil {
+		tAPI.Close()
+		return nil, fmt.Errorf("could not load wasm module: %w", err)
 	}
 
-	return &tAPI
+	return tAPI, nil
 }
 
-func fun(ctx context.Context, mod api.Module, name string) func(params ...uint64) []uint64 {
-	funDef := mod.ExportedFunction(name)
+// fun binds the exported function `name`. Missing exports and traps are reported
+// as *WasmError instead of panicking, and after the first trap every later call
+// fails fast, because the memory of the module may be corrupted.
+// When the context of the call is done, wazero closes the module and ctx.Err() is returned.
+func (t *tesseractApi) fun(name string) func(params ...uint64) ([]uint64, error) {
+	funDef := t.module.ExportedFunction(name)
 
-	return func(params ...uint64) []uint64 {
-		r, err := funDef.Call(ctx, params...)
+	return func(params ...uint64) ([]uint64, error) {
+		if funDef == nil {
+			return nil, &WasmError{Func: name, Kind: ErrMissingExport}
+		}
+		if t.err != nil {
+			return nil, t.err
+		}
+		r, err := funDef.Call(t.context, params...)
 		if err != nil {
-			panic(err)
+			if ctxErr := t.context.Err(); ctxErr != nil {
+				t.err = &WasmError{Func: name, Kind: ErrClientClosed, Err: ctxErr}
+				return nil, ctxErr
+			}
+			t.err = &WasmError{Func: name, Kind: ErrWasmTrap, Err: err}
+			return nil, t.err
 		}
-		return r
+		return r, nil
 	}
 }
 
 type tesseractApi struct {
-	module  api.Module
+	module api.Module
+	// runtime is only set if the module has a runtime of its own, which is closed along with the module.
+	runtime wazero.Runtime
+	// release untracks the runtime from the engine which closes it otherwise, see Engine.Close.
+	release func()
+	// context is passed to every call, see useContext.
 	context context.Context
+	// err is the first trap of this module, see fun.
+	err error
+	// sandbox holds the filesystem mounts of this module.
+	sandbox *sandbox
 	Create,
 	Free,
 	free,
@@ -137,39 +220,200 @@ type tesseractApi struct {
 	Init,
 	GetBoundingBoxes,
 	GetBoundingBoxesVerbose,
+	GetResults,
+	AnalyseLayout,
+	GetSymbolChoices,
 	SetVariable,
 	SetPixImage,
+	SetRectangle,
+	SetSourceResolution,
+	GetSourceResolution,
 	SetPageSegMode,
 	GetPageSegMode,
 	Utf8Text,
 	HocrText,
+	TSVText,
+	BoxText,
+	LSTMBoxText,
+	WordStrBoxText,
+	AllWordConfidences,
 	Version,
 	FileExists,
 	GetDataPath,
 	CreatePixImageByFilepath,
 	CreatePixImageFromBytes,
-	DestroyPixImage func(params ...uint64) []uint64
+	CreatePix,
+	GetPixInfo,
+	DestroyPixImage,
+	HasLegacyEngine func(params ...uint64) ([]uint64, error)
+}
+
+// useContext passes ctx to the following calls, until the returned func is called.
+func (t *tesseractApi) useContext(ctx context.Context) (restore func()) {
+	prev := t.context
+	t.context = ctx
+	return func() {
+		t.context = prev
+	}
+}
+
+func (t *tesseractApi) Close() error {
+	err := t.module.Close(t.context)
+	if t.release != nil {
+		t.release()
+	}
+	if t.runtime != nil {
+		if rerr := t.runtime.Close(t.context); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// hasLegacyEngine reports whether tesseract was built with the legacy engine.
+// Binaries built before HasLegacyEngine was exported report ErrMissingExport:
+// their Init ignores the OCR engine mode and always runs the LSTM engine.
+func (t *tesseractApi) hasLegacyEngine() (bool, error) {
+	res, err := t.HasLegacyEngine()
+	if err != nil {
+		return false, err
+	}
+	return res[0] != 0, nil
+}
+
+// setPixImage passes pix to TessBaseAPI, raising its DPI to 70 if it's lower and clamp is set.
+// Binaries built before SetPixImage took clamp always raise it, see pixImageTakesClamp.
+func (t *tesseractApi) setPixImage(api, pix uint64, clamp bool) error {
+	if !t.pixImageTakesClamp() {
+		_, err := t.SetPixImage(api, pix)
+		return err
+	}
+	var c uint64
+	if clamp {
+		c = 1
+	}
+	_, err := t.SetPixImage(api, pix, c)
+	return err
+}
+
+// pixImageTakesClamp reports whether SetPixImage of the module has the clamp argument.
+func (t *tesseractApi) pixImageTakesClamp() bool {
+	fn := t.module.ExportedFunction("SetPixImage")
+	return fn != nil && len(fn.Definition().ParamTypes()) == 3
+}
+
+// requireExport reports ErrMissingExport if the module doesn't export name. Options relying on
+// an export check it when they are set, so the recognition doesn't fail because of them later.
+func (t *tesseractApi) requireExport(name string) error {
+	if t.module.ExportedFunction(name) == nil {
+		return &WasmError{Func: name, Kind: ErrMissingExport}
+	}
+	return nil
+}
+
+// alloc allocates size bytes in the module memory, which must be released with free.
+func (t *tesseractApi) alloc(size uint64) (uint64, error) {
+	res, err := t.malloc(size)
+	if err != nil {
+		return 0, err
+	}
+	if res[0] == 0 {
+		return 0, &WasmError{Func: "malloc", Kind: ErrOutOfMemory, Err: fmt.Errorf("could not allocate %d bytes", size)}
+	}
+	return res[0], nil
+}
+
+// WriteBytes copies data into newly allocated module memory, which must be released with free.
+func (t *tesseractApi) WriteBytes(data []byte) (uint64, error) {
+	ptr, err := t.alloc(uint64(len(data)))
+	if err != nil {
+		return 0, err
+	}
+	if !t.module.Memory().Write(uint32(ptr), data) {
+		t.free(ptr)
+		return 0, &WasmError{Func: "malloc", Kind: ErrOutOfMemory, Err: fmt.Errorf("could not write %d bytes", len(data))}
+	}
+	return ptr, nil
+}
+
+// readAll streams r into newly allocated module memory, which must be released with free.
+// The buffer is doubled whenever it is full, and ErrImageTooLarge is returned after maxBytes.
+func (t *tesseractApi) readAll(r io.Reader, maxBytes int64) (ptr uint64, size uint64, err error) {
+	capacity := uint64(16 * 1024)
+	if capacity > uint64(maxBytes) {
+		capacity = uint64(maxBytes)
+	}
+	if ptr, err = t.alloc(capacity); err != nil {
+		return 0, 0, err
+	}
+	chunk := make([]byte, 32*1024)
+	for {
+		n, rerr := r.Read(chunk)
+		if n > 0 {
+			if size+uint64(n) > uint64(maxBytes) {
+				t.free(ptr)
+				return 0, 0, fmt.Errorf("%w: more than %d bytes", ErrImageTooLarge, maxBytes)
+			}
+			if size+uint64(n) > capacity {
+				for capacity < size+uint64(n) {
+					capacity *= 2
+				}
+				if capacity > uint64(maxBytes) {
+					capacity = uint64(maxBytes)
+				}
+				grown, err := t.alloc(capacity)
+				if err != nil {
+					t.free(ptr)
+					return 0, 0, err
+				}
+				// Read after alloc, as the memory may have grown and moved.
+				data, ok := t.module.Memory().Read(uint32(ptr), uint32(size))
+				if ok {
+					ok = t.module.Memory().Write(uint32(grown), data)
+				}
+				t.free(ptr)
+				ptr = grown
+				if !ok {
+					t.free(ptr)
+					return 0, 0, &WasmError{Func: "malloc", Kind: ErrOutOfMemory, Err: fmt.Errorf("could not write %d bytes", size)}
+				}
+			}
+			if !t.module.Memory().Write(uint32(ptr+size), chunk[:n]) {
+				t.free(ptr)
+				return 0, 0, &WasmError{Func: "malloc", Kind: ErrOutOfMemory, Err: fmt.Errorf("could not write %d bytes", n)}
+			}
+			size += uint64(n)
+		}
+		if rerr == io.EOF {
+			return ptr, size, nil
+		}
+		if rerr != nil {
+			t.free(ptr)
+			return 0, 0, rerr
+		}
+	}
 }
 
-func (t *tesseractApi) Close() {
-	t.module.Close(t.context)
+// WriteString copies s as null terminated string into module memory, which must be released with free.
+func (t *tesseractApi) WriteString(s string) (uint64, error) {
+	return t.WriteBytes(append([]byte(s), 0))
 }
 
-func (t *tesseractApi) ReadString(ptr uint64) string {
+func (t *tesseractApi) ReadString(ptr uint64) (string, error) {
 	if ptr == 0 || ptr == 0xffffffff {
-		return ""
+		return "", nil
 	}
 	mem := t.module.Memory()
 	buf, ok := mem.Read(uint32(ptr), math.MaxUint32)
 	if !ok {
 		buf, ok = mem.Read(uint32(ptr), mem.Size()-uint32(ptr))
 		if !ok {
-			panic("range error")
+			return "", &WasmError{Func: "ReadString", Kind: ErrWasmTrap, Err: fmt.Errorf("pointer %d is out of range", ptr)}
 		}
 	}
 	if i := bytes.IndexByte(buf, 0); i < 0 {
-		panic("string is not null terminated")
+		return "", &WasmError{Func: "ReadString", Kind: ErrWasmTrap, Err: fmt.Errorf("string is not null terminated")}
 	} else {
-		return string(buf[:i])
+		return string(buf[:i]), nil
 	}
 }